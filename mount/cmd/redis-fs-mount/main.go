@@ -6,6 +6,7 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/exec"
 	"os/signal"
@@ -13,20 +14,35 @@ import (
 	"syscall"
 	"time"
 
-	"github.com/redis/go-redis/v9"
 	"github.com/redis-fs/mount/internal/client"
 	"github.com/redis-fs/mount/internal/redisfs"
+	"github.com/redis/go-redis/v9"
 )
 
 func main() {
-	redisAddr := flag.String("redis", "localhost:6379", "Redis server address")
+	redisAddr := flag.String("redis", "localhost:6379", "Redis server address (standalone mode)")
 	redisPassword := flag.String("password", "", "Redis password")
-	redisDB := flag.Int("db", 0, "Redis database number")
+	redisDB := flag.Int("db", 0, "Redis database number (standalone and Sentinel modes only)")
+	sentinelMaster := flag.String("sentinel-master", "", "Sentinel master name; when set, connect via Sentinel instead of --redis")
+	sentinelAddrs := flag.String("sentinel-addrs", "", "Comma-separated Sentinel addresses, required with --sentinel-master")
+	sentinelPassword := flag.String("sentinel-password", "", "Password for the Sentinel nodes themselves, if set")
+	clusterAddrs := flag.String("cluster-addrs", "", "Comma-separated Redis Cluster seed node addresses; when set, connect via Cluster instead of --redis")
 	attrTimeout := flag.Float64("attr-timeout", 1.0, "Attribute cache TTL in seconds")
+	maxCacheEntries := flag.Int("max-cache-entries", 0, "Max entries per cache (attr/dir/xattr) before LRU eviction; 0 uses the built-in default")
+	negativeCacheTimeout := flag.Float64("negative-cache-timeout", 0, "Negative Lookup/Getattr cache TTL in seconds; 0 defaults to --attr-timeout")
 	readOnly := flag.Bool("readonly", false, "Mount read-only")
 	allowOther := flag.Bool("allow-other", false, "Allow other users to access mount")
 	foreground := flag.Bool("foreground", true, "Run in foreground")
 	debug := flag.Bool("debug", false, "Enable FUSE debug logging")
+	disableXattr := flag.Bool("disable-xattr", false, "Disable extended attribute support (Getxattr/Setxattr/Listxattr/Removexattr all return ENOTSUP)")
+	disableReadDirPlus := flag.Bool("disable-readdirplus", false, "Disable READDIRPLUS attr-cache pre-population, falling back to a Lookup round-trip per directory entry")
+	subscribeInvalidations := flag.Bool("subscribe-invalidations", false, "Subscribe to cross-mount cache invalidations")
+	watch := flag.Bool("watch", false, "Alias for --subscribe-invalidations")
+	invalidationChannel := flag.String("invalidation-channel", client.DefaultInvalidationChannel, "Pub/sub channel for cross-mount cache invalidations")
+	invalidationPollInterval := flag.Duration("invalidation-poll-interval", 0, "If set, periodically resync caches wholesale on this interval; a fallback (or backstop) for Redis servers that can't publish to --invalidation-channel")
+	metricsAddr := flag.String("metrics-addr", "", "If set, serve Prometheus metrics at http://<addr>/metrics")
+	logFile := flag.String("log-file", "", "If set, append diagnostic output to this file instead of stderr; also used for the daemon-mode child's stdout/stderr so backgrounded mounts stay debuggable")
+	encryptPassphrase := flag.String("encrypt-passphrase", "", "If set, mount in encrypted mode: filenames and file content are AES-encrypted, decrypted only in this process. Also honors REDIS_FS_ENCRYPT_PASSPHRASE")
 
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage: %s [flags] <redis-key> <mountpoint>\n\n", os.Args[0])
@@ -60,14 +76,29 @@ func main() {
 
 		cmd := exec.Command(os.Args[0], args...)
 		cmd.Env = append(os.Environ(), "REDIS_FS_DAEMON=1")
+
 		devNull, err := os.OpenFile(os.DevNull, os.O_RDWR, 0)
 		if err != nil {
 			log.Fatalf("daemon mode failed opening %s: %v", os.DevNull, err)
 		}
 		defer devNull.Close()
 		cmd.Stdin = devNull
+
+		// Without --log-file the daemon's stdout/stderr (and thus every
+		// log.Printf/Logger write, since none of them goes to Redis) is
+		// discarded, which makes a backgrounded mount undebuggable. Route
+		// them to the log file instead when one was given.
 		cmd.Stdout = devNull
 		cmd.Stderr = devNull
+		if *logFile != "" {
+			lf, err := os.OpenFile(*logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+			if err != nil {
+				log.Fatalf("daemon mode failed opening --log-file %s: %v", *logFile, err)
+			}
+			defer lf.Close()
+			cmd.Stdout = lf
+			cmd.Stderr = lf
+		}
 		cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
 		if err := cmd.Start(); err != nil {
 			log.Fatalf("daemon mode failed: %v", err)
@@ -79,6 +110,17 @@ func main() {
 	redisKey := flag.Arg(0)
 	mountpoint := flag.Arg(1)
 
+	logger := log.Default()
+	if *logFile != "" {
+		lf, err := os.OpenFile(*logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			log.Fatalf("failed opening --log-file %s: %v", *logFile, err)
+		}
+		defer lf.Close()
+		logger = log.New(lf, "", log.LstdFlags)
+		log.SetOutput(lf)
+	}
+
 	// Verify mountpoint exists.
 	fi, err := os.Stat(mountpoint)
 	if err != nil {
@@ -88,40 +130,77 @@ func main() {
 		log.Fatalf("mountpoint %s is not a directory", mountpoint)
 	}
 
-	// Connect to Redis.
-	rdb := redis.NewClient(&redis.Options{
-		Addr:     *redisAddr,
-		Password: *redisPassword,
-		DB:       *redisDB,
-		PoolSize: 16,
+	// Connect to Redis: standalone, Sentinel-managed failover, or Cluster,
+	// depending on which topology flags were passed.
+	rdb, topology, err := connectRedis(redisConnOptions{
+		addr:             *redisAddr,
+		password:         *redisPassword,
+		db:               *redisDB,
+		sentinelMaster:   *sentinelMaster,
+		sentinelAddrs:    *sentinelAddrs,
+		sentinelPassword: *sentinelPassword,
+		clusterAddrs:     *clusterAddrs,
 	})
+	if err != nil {
+		log.Fatalf("invalid Redis connection flags: %v", err)
+	}
 
 	ctx := context.Background()
 	if err := rdb.Ping(ctx).Err(); err != nil {
-		log.Fatalf("cannot connect to Redis at %s: %v", *redisAddr, err)
+		log.Fatalf("cannot connect to Redis (%s): %v", topology, err)
 	}
 
 	c := client.New(rdb, redisKey)
 
 	uid, gid := redisfs.GetOwnership()
 
+	passphrase := *encryptPassphrase
+	if passphrase == "" {
+		passphrase = os.Getenv("REDIS_FS_ENCRYPT_PASSPHRASE")
+	}
+	var encryptionPassphrase []byte
+	if passphrase != "" {
+		encryptionPassphrase = []byte(passphrase)
+	}
+
 	opts := &redisfs.Options{
-		AttrTimeout: time.Duration(*attrTimeout * float64(time.Second)),
-		ReadOnly:    *readOnly,
-		AllowOther:  *allowOther,
-		Debug:       *debug,
-		UID:         uid,
-		GID:         gid,
+		AttrTimeout:              time.Duration(*attrTimeout * float64(time.Second)),
+		ReadOnly:                 *readOnly,
+		AllowOther:               *allowOther,
+		Debug:                    *debug,
+		UID:                      uid,
+		GID:                      gid,
+		DisableXattr:             *disableXattr,
+		DisableReadDirPlus:       *disableReadDirPlus,
+		MaxCacheEntries:          *maxCacheEntries,
+		NegativeCacheTimeout:     time.Duration(*negativeCacheTimeout * float64(time.Second)),
+		SubscribeInvalidations:   *subscribeInvalidations || *watch,
+		InvalidationChannel:      *invalidationChannel,
+		InvalidationPollInterval: *invalidationPollInterval,
+		EnableMetrics:            *metricsAddr != "",
+		Logger:                   logger,
+		EncryptionPassphrase:     encryptionPassphrase,
 	}
 
 	log.Printf("Mounting Redis FS key %q at %s", redisKey, mountpoint)
-	log.Printf("Redis: %s (db %d)", *redisAddr, *redisDB)
+	log.Printf("Redis: %s", topology)
 
-	server, err := redisfs.Mount(mountpoint, c, opts)
+	server, root, err := redisfs.Mount(mountpoint, c, opts)
 	if err != nil {
 		log.Fatalf("mount failed: %v", err)
 	}
 
+	if *metricsAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", root.MetricsHandler())
+		go func() {
+			log.Printf("Serving Prometheus metrics at http://%s/metrics", *metricsAddr)
+			if err := http.ListenAndServe(*metricsAddr, mux); err != nil {
+				log.Printf("metrics server error: %v", err)
+			}
+		}()
+	}
+
 	// Handle shutdown signals.
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
@@ -141,3 +220,75 @@ func main() {
 
 	rdb.Close()
 }
+
+// redisConnOptions holds the flags needed to build a connection to any of
+// the three topologies this command supports.
+type redisConnOptions struct {
+	addr     string
+	password string
+	db       int
+
+	sentinelMaster   string
+	sentinelAddrs    string
+	sentinelPassword string
+
+	clusterAddrs string
+}
+
+// connectRedis builds a redis.UniversalClient for standalone, Sentinel, or
+// Cluster, chosen by which topology flags were set, and returns a short
+// description of the topology for logging. At most one of sentinelMaster
+// or clusterAddrs may be set.
+func connectRedis(o redisConnOptions) (redis.UniversalClient, string, error) {
+	if o.sentinelMaster != "" && o.clusterAddrs != "" {
+		return nil, "", fmt.Errorf("--sentinel-master and --cluster-addrs are mutually exclusive")
+	}
+
+	switch {
+	case o.sentinelMaster != "":
+		addrs := splitAddrList(o.sentinelAddrs)
+		if len(addrs) == 0 {
+			return nil, "", fmt.Errorf("--sentinel-addrs is required with --sentinel-master")
+		}
+		rdb := redis.NewUniversalClient(&redis.UniversalOptions{
+			MasterName:       o.sentinelMaster,
+			Addrs:            addrs,
+			SentinelPassword: o.sentinelPassword,
+			Password:         o.password,
+			DB:               o.db,
+		})
+		return rdb, fmt.Sprintf("sentinel master=%s via %s", o.sentinelMaster, o.sentinelAddrs), nil
+
+	case o.clusterAddrs != "":
+		addrs := splitAddrList(o.clusterAddrs)
+		if len(addrs) == 0 {
+			return nil, "", fmt.Errorf("--cluster-addrs must list at least one seed node")
+		}
+		rdb := redis.NewUniversalClient(&redis.UniversalOptions{
+			Addrs:    addrs,
+			Password: o.password,
+		})
+		return rdb, fmt.Sprintf("cluster seeds=%s", o.clusterAddrs), nil
+
+	default:
+		rdb := redis.NewClient(&redis.Options{
+			Addr:     o.addr,
+			Password: o.password,
+			DB:       o.db,
+			PoolSize: 16,
+		})
+		return rdb, fmt.Sprintf("standalone %s (db %d)", o.addr, o.db), nil
+	}
+}
+
+// splitAddrList splits a comma-separated address list, dropping empty entries.
+func splitAddrList(s string) []string {
+	var out []string
+	for _, a := range strings.Split(s, ",") {
+		a = strings.TrimSpace(a)
+		if a != "" {
+			out = append(out, a)
+		}
+	}
+	return out
+}
@@ -0,0 +1,718 @@
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// manifestName is the first entry in every backup archive: a small JSON
+// descriptor restore uses to validate the archive before touching the
+// target key.
+const manifestName = "MANIFEST.json"
+
+// defaultBackupChunkSize bounds how much of one file's content writeArchive
+// and restoreArchive hold in memory at a time, mirroring
+// defaultImportChunkSize's role in import.go.
+const defaultBackupChunkSize = 4 * 1024 * 1024
+
+// archiveManifest describes a backup archive's contents. Checksum lets
+// restore detect truncation or tampering before it starts overwriting data.
+type archiveManifest struct {
+	RedisKey     string    `json:"redis_key"`
+	RedisVersion string    `json:"redis_version"`
+	CreatedAt    time.Time `json:"created_at"`
+	Files        int       `json:"files"`
+	Directories  int       `json:"directories"`
+	Symlinks     int       `json:"symlinks"`
+	Checksum     string    `json:"checksum"` // sha256 over the metadata of every entry, in archive order
+}
+
+// fsMeta holds the metadata FS.STAT reports for one path.
+type fsMeta struct {
+	typ              string // "file", "dir", "symlink"
+	mode             uint32
+	uid, gid         uint32
+	size             int64
+	atimeMs, mtimeMs int64
+}
+
+func cmdBackup() error {
+	fs := flag.NewFlagSet("backup", flag.ExitOnError)
+	redisAddr := fs.String("redis", "localhost:6379", "Redis address (host:port)")
+	redisPassword := fs.String("password", "", "Redis password")
+	redisDB := fs.Int("db", 0, "Redis DB number")
+	redisKey := fs.String("key", "myfs", "Redis filesystem key to back up")
+	useRDB := fs.Bool("use-rdb", false, "Also trigger BGSAVE/BGREWRITEAOF and copy the resulting RDB/AOF next to the archive")
+	if err := fs.Parse(os.Args[2:]); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: rfs backup [flags] <archive.rfs.tar.gz>")
+	}
+	archivePath := fs.Arg(0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr:     *redisAddr,
+		Password: *redisPassword,
+		DB:       *redisDB,
+		PoolSize: 8,
+	})
+	defer rdb.Close()
+
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("cannot connect to Redis at %s: %w", *redisAddr, err)
+	}
+	if err := ensureFSModuleLoaded(ctx, rdb); err != nil {
+		return err
+	}
+
+	redisVersion, err := redisVersion(ctx, rdb)
+	if err != nil {
+		return err
+	}
+
+	entries, err := walkFS(ctx, rdb, *redisKey, "/")
+	if err != nil {
+		return fmt.Errorf("walk key %q: %w", *redisKey, err)
+	}
+
+	manifest := archiveManifest{
+		RedisKey:     *redisKey,
+		RedisVersion: redisVersion,
+		CreatedAt:    time.Now().UTC(),
+		Checksum:     checksumEntries(entries),
+	}
+	for _, e := range entries {
+		switch e.meta.typ {
+		case "dir":
+			manifest.Directories++
+		case "symlink":
+			manifest.Symlinks++
+		default:
+			manifest.Files++
+		}
+	}
+
+	if err := writeArchive(ctx, rdb, *redisKey, archivePath, manifest, entries); err != nil {
+		return err
+	}
+	fmt.Printf("Backed up %d files, %d directories, %d symlinks from key %q to %s\n",
+		manifest.Files, manifest.Directories, manifest.Symlinks, *redisKey, archivePath)
+
+	if *useRDB {
+		dst, err := backupRDBOrAOF(ctx, rdb, archivePath)
+		if err != nil {
+			return fmt.Errorf("--use-rdb: %w", err)
+		}
+		fmt.Printf("Copied Redis persistence file(s) to %s\n", dst)
+	}
+
+	return nil
+}
+
+func cmdRestore() error {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	redisAddr := fs.String("redis", "localhost:6379", "Redis address (host:port)")
+	redisPassword := fs.String("password", "", "Redis password")
+	redisDB := fs.Int("db", 0, "Redis DB number")
+	redisKey := fs.String("key", "", "Redis filesystem key to restore into (defaults to the key recorded in the archive)")
+	force := fs.Bool("force", false, "Overwrite an existing key without prompting")
+	if err := fs.Parse(os.Args[2:]); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: rfs restore [flags] <archive.rfs.tar.gz>")
+	}
+	archivePath := fs.Arg(0)
+
+	manifest, entries, err := readArchiveManifest(archivePath)
+	if err != nil {
+		return fmt.Errorf("archive %s is invalid: %w", archivePath, err)
+	}
+	if got := checksumEntries(entries); got != manifest.Checksum {
+		return fmt.Errorf("archive %s failed checksum validation (manifest says %s, archive contains %s)", archivePath, manifest.Checksum, got)
+	}
+
+	key := *redisKey
+	if key == "" {
+		key = manifest.RedisKey
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr:     *redisAddr,
+		Password: *redisPassword,
+		DB:       *redisDB,
+		PoolSize: 8,
+	})
+	defer rdb.Close()
+
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("cannot connect to Redis at %s: %w", *redisAddr, err)
+	}
+	if err := ensureFSModuleLoaded(ctx, rdb); err != nil {
+		return err
+	}
+
+	exists, err := rdb.Exists(ctx, key).Result()
+	if err != nil {
+		return err
+	}
+	if exists > 0 && !*force {
+		ok, err := promptYesNo(bufio.NewReader(os.Stdin), os.Stdout, fmt.Sprintf("Redis key %q already exists. Overwrite it?", key), false)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("restore cancelled")
+		}
+	}
+	if exists > 0 {
+		if err := rdb.Del(ctx, key).Err(); err != nil {
+			return fmt.Errorf("delete existing redis key: %w", err)
+		}
+	}
+
+	if err := restoreArchive(ctx, rdb, key, archivePath, entries); err != nil {
+		return err
+	}
+
+	fmt.Printf("Restored %d files, %d directories, %d symlinks from %s into key %q\n",
+		manifest.Files, manifest.Directories, manifest.Symlinks, archivePath, key)
+	return nil
+}
+
+// fsEntry is one path: its metadata, plus a symlink target when
+// meta.typ == "symlink". File content is never held here; it's streamed
+// straight from Redis (writeArchive) or the tar entry (restoreArchive) in
+// defaultBackupChunkSize pieces instead.
+type fsEntry struct {
+	path   string
+	meta   fsMeta
+	target string // symlink target, only set when meta.typ == "symlink"
+}
+
+// walkFS recursively lists the children of root (an FS path, e.g. "/") and
+// returns every descendant with its metadata, in parent-before-child order,
+// so a later restore can FS.MKDIR/FS.ECHO/FS.LN in the same order without
+// hitting a missing-parent error.
+func walkFS(ctx context.Context, rdb *redis.Client, key, root string) ([]fsEntry, error) {
+	var entries []fsEntry
+	var walk func(dir string) error
+	walk = func(dir string) error {
+		names, err := rdb.Do(ctx, "FS.LS", key, dir).StringSlice()
+		if err != nil {
+			return fmt.Errorf("FS.LS %s: %w", dir, err)
+		}
+		for _, name := range names {
+			childPath := path.Join(dir, name)
+			meta, err := statFS(ctx, rdb, key, childPath)
+			if err != nil {
+				return fmt.Errorf("FS.STAT %s: %w", childPath, err)
+			}
+			e := fsEntry{path: childPath, meta: *meta}
+			if meta.typ == "symlink" {
+				target, err := rdb.Do(ctx, "FS.READLINK", key, childPath).Text()
+				if err != nil {
+					return fmt.Errorf("FS.READLINK %s: %w", childPath, err)
+				}
+				e.target = target
+			}
+			entries = append(entries, e)
+			if meta.typ == "dir" {
+				if err := walk(childPath); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := walk(root); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// statFS parses FS.STAT's flat [field, value, ...] response into an fsMeta.
+func statFS(ctx context.Context, rdb *redis.Client, key, path string) (*fsMeta, error) {
+	res, err := rdb.Do(ctx, "FS.STAT", key, path).Slice()
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]interface{}, 8)
+	for i := 0; i+1 < len(res); i += 2 {
+		if k, ok := res[i].(string); ok {
+			m[k] = res[i+1]
+		}
+	}
+	mode, _ := strconv.ParseUint(fmt.Sprintf("%v", m["mode"]), 8, 32)
+	return &fsMeta{
+		typ:     fmt.Sprintf("%v", m["type"]),
+		mode:    uint32(mode),
+		uid:     uint32(toInt64(m["uid"])),
+		gid:     uint32(toInt64(m["gid"])),
+		size:    toInt64(m["size"]),
+		atimeMs: toInt64(m["atime"]),
+		mtimeMs: toInt64(m["mtime"]),
+	}, nil
+}
+
+func toInt64(v interface{}) int64 {
+	switch val := v.(type) {
+	case int64:
+		return val
+	case int:
+		return int64(val)
+	case string:
+		n, _ := strconv.ParseInt(val, 10, 64)
+		return n
+	default:
+		return 0
+	}
+}
+
+// checksumEntries hashes the metadata of every entry, in order, so restore
+// can tell an archive apart from a truncated or hand-edited one. Content
+// bytes are deliberately excluded: hashing every file's content would mean
+// reading the whole archive twice, once to check it and once to restore it.
+func checksumEntries(entries []fsEntry) string {
+	h := sha256.New()
+	for _, e := range entries {
+		size := e.meta.size
+		if e.meta.typ != "file" {
+			size = 0
+		}
+		fmt.Fprintf(h, "%s\t%s\t%04o\t%d\t%d\t%d\n", e.meta.typ, e.path, e.meta.mode, e.meta.uid, e.meta.gid, size)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// writeArchive streams entries and the manifest into a gzip-compressed tar
+// file at archivePath, fetching each file's content from Redis as its tar
+// entry is written rather than buffering the whole backup in memory.
+func writeArchive(ctx context.Context, rdb *redis.Client, key, archivePath string, manifest archiveManifest, entries []fsEntry) (err error) {
+	f, err := os.Create(archivePath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	gz := gzip.NewWriter(f)
+	defer func() {
+		if cerr := gz.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	tw := tar.NewWriter(gz)
+	defer func() {
+		if cerr := tw.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	manifestJSON, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: manifestName, Size: int64(len(manifestJSON)), Mode: 0o644}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(manifestJSON); err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		hdr := &tar.Header{
+			Name:       strings.TrimPrefix(e.path, "/"),
+			Mode:       int64(e.meta.mode),
+			Uid:        int(e.meta.uid),
+			Gid:        int(e.meta.gid),
+			ModTime:    time.UnixMilli(e.meta.mtimeMs),
+			AccessTime: time.UnixMilli(e.meta.atimeMs),
+		}
+		switch e.meta.typ {
+		case "dir":
+			hdr.Typeflag = tar.TypeDir
+		case "symlink":
+			hdr.Typeflag = tar.TypeSymlink
+			hdr.Linkname = e.target
+		default:
+			hdr.Typeflag = tar.TypeReg
+			hdr.Size = e.meta.size
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return fmt.Errorf("tar header for %s: %w", e.path, err)
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			if err := writeFileContentChunked(ctx, rdb, key, e.path, e.meta.size, tw); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeFileContentChunked copies path's content from Redis into w
+// defaultBackupChunkSize bytes at a time via repeated FS.READ calls, rather
+// than fetching the whole file in one FS.CAT.
+func writeFileContentChunked(ctx context.Context, rdb *redis.Client, key, path string, size int64, w io.Writer) error {
+	for offset := int64(0); offset < size; {
+		length := int64(defaultBackupChunkSize)
+		if remaining := size - offset; remaining < length {
+			length = remaining
+		}
+		data, err := rdb.Do(ctx, "FS.READ", key, path, offset, length).Result()
+		if err != nil {
+			return fmt.Errorf("FS.READ %s at offset %d: %w", path, offset, err)
+		}
+		content, ok := data.(string)
+		if !ok {
+			return fmt.Errorf("unexpected READ response type for %s: %T", path, data)
+		}
+		if len(content) == 0 {
+			break
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			return fmt.Errorf("write content for %s: %w", path, err)
+		}
+		offset += int64(len(content))
+	}
+	return nil
+}
+
+// readArchiveManifest reads back the manifest and every entry's metadata
+// from a backup archive written by writeArchive, without touching Redis and
+// without reading any file's content: tar.Reader.Next discards whatever of
+// the current entry goes unread, so this never holds a single file -- let
+// alone the whole archive -- in memory. Checksum validation only needs this
+// metadata, so restore can verify the archive before opening it again (in
+// restoreArchive) to actually stream content into Redis.
+func readArchiveManifest(archivePath string) (archiveManifest, []fsEntry, error) {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return archiveManifest{}, nil, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return archiveManifest{}, nil, err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+
+	hdr, err := tr.Next()
+	if err != nil {
+		return archiveManifest{}, nil, fmt.Errorf("read manifest: %w", err)
+	}
+	if hdr.Name != manifestName {
+		return archiveManifest{}, nil, fmt.Errorf("archive does not start with %s", manifestName)
+	}
+	var manifest archiveManifest
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return archiveManifest{}, nil, fmt.Errorf("decode manifest: %w", err)
+	}
+
+	var entries []fsEntry
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return archiveManifest{}, nil, err
+		}
+		e := fsEntry{
+			path: "/" + hdr.Name,
+			meta: fsMeta{
+				mode:    uint32(hdr.Mode),
+				uid:     uint32(hdr.Uid),
+				gid:     uint32(hdr.Gid),
+				size:    hdr.Size,
+				atimeMs: hdr.AccessTime.UnixMilli(),
+				mtimeMs: hdr.ModTime.UnixMilli(),
+			},
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			e.meta.typ = "dir"
+		case tar.TypeSymlink:
+			e.meta.typ = "symlink"
+			e.target = hdr.Linkname
+		default:
+			e.meta.typ = "file"
+		}
+		entries = append(entries, e)
+	}
+	return manifest, entries, nil
+}
+
+// restoreArchive re-opens archivePath and replays entries (as already read
+// by readArchiveManifest) into key, streaming each file's content straight
+// from its tar entry to Redis via writeFileContentFromReaderChunked instead
+// of buffering it first. It re-scans the archive independently of
+// readArchiveManifest's pass, so each tar header is checked against the
+// corresponding entry's path before being applied -- if archivePath changed
+// between the two passes, this fails loudly instead of silently pairing one
+// file's metadata with another file's content.
+func restoreArchive(ctx context.Context, rdb *redis.Client, key, archivePath string, entries []fsEntry) error {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	if _, err := tr.Next(); err != nil { // manifest entry, already validated
+		return fmt.Errorf("read manifest: %w", err)
+	}
+
+	for _, e := range entries {
+		hdr, err := tr.Next()
+		if err != nil {
+			return fmt.Errorf("read archive entry for %s: %w", e.path, err)
+		}
+		if got := "/" + hdr.Name; got != e.path {
+			return fmt.Errorf("archive changed since it was validated: expected %s next, found %s", e.path, got)
+		}
+		if err := applyEntry(ctx, rdb, key, e, tr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyEntry replays one archived entry into key using the same FS.*
+// sequence importDirectory uses for local directories. For a file entry, r
+// is positioned at that entry's content in the tar stream; applyEntry reads
+// it defaultBackupChunkSize bytes at a time instead of buffering it whole.
+func applyEntry(ctx context.Context, rdb *redis.Client, key string, e fsEntry, r io.Reader) error {
+	switch e.meta.typ {
+	case "dir":
+		if err := rdb.Do(ctx, "FS.MKDIR", key, e.path, "PARENTS").Err(); err != nil {
+			return fmt.Errorf("FS.MKDIR %s: %w", e.path, err)
+		}
+	case "symlink":
+		if err := rdb.Do(ctx, "FS.LN", key, e.target, e.path).Err(); err != nil {
+			return fmt.Errorf("FS.LN %s: %w", e.path, err)
+		}
+	default:
+		if err := writeFileContentFromReaderChunked(ctx, rdb, key, e.path, r); err != nil {
+			return err
+		}
+	}
+
+	modeStr := fmt.Sprintf("%04o", e.meta.mode)
+	if err := rdb.Do(ctx, "FS.CHMOD", key, e.path, modeStr).Err(); err != nil {
+		return fmt.Errorf("FS.CHMOD %s: %w", e.path, err)
+	}
+	if err := rdb.Do(ctx, "FS.CHOWN", key, e.path, e.meta.uid, e.meta.gid).Err(); err != nil {
+		return fmt.Errorf("FS.CHOWN %s: %w", e.path, err)
+	}
+	if err := rdb.Do(ctx, "FS.UTIMENS", key, e.path, e.meta.atimeMs, e.meta.mtimeMs).Err(); err != nil {
+		return fmt.Errorf("FS.UTIMENS %s: %w", e.path, err)
+	}
+	return nil
+}
+
+// writeFileContentFromReaderChunked reads r (a tar entry's content) in
+// defaultBackupChunkSize pieces and writes them to path via repeated
+// FS.ECHO calls (the first plain, the rest APPEND), mirroring
+// importFileChunked's approach for the same reason: a single archived file
+// can be far larger than fits comfortably in memory at once.
+func writeFileContentFromReaderChunked(ctx context.Context, rdb *redis.Client, key, path string, r io.Reader) error {
+	buf := make([]byte, defaultBackupChunkSize)
+	offset := int64(0)
+	for {
+		n, readErr := r.Read(buf)
+		if n > 0 {
+			chunk := buf[:n]
+			var writeErr error
+			if offset == 0 {
+				writeErr = rdb.Do(ctx, "FS.ECHO", key, path, chunk).Err()
+			} else {
+				writeErr = rdb.Do(ctx, "FS.ECHO", key, path, chunk, "APPEND").Err()
+			}
+			if writeErr != nil {
+				return fmt.Errorf("FS.ECHO %s at offset %d: %w", path, offset, writeErr)
+			}
+			offset += int64(n)
+		}
+		if readErr == io.EOF {
+			if offset == 0 {
+				// Empty file: still need an explicit FS.ECHO so it round-trips
+				// as present rather than staying absent.
+				return rdb.Do(ctx, "FS.ECHO", key, path, []byte{}).Err()
+			}
+			return nil
+		}
+		if readErr != nil {
+			return fmt.Errorf("read content for %s: %w", path, readErr)
+		}
+	}
+}
+
+// redisVersion reads the server's version for the archive manifest.
+func redisVersion(ctx context.Context, rdb *redis.Client) (string, error) {
+	info, err := rdb.Info(ctx, "server").Result()
+	if err != nil {
+		return "", fmt.Errorf("INFO server: %w", err)
+	}
+	for _, line := range strings.Split(info, "\r\n") {
+		if v, ok := strings.CutPrefix(line, "redis_version:"); ok {
+			return v, nil
+		}
+	}
+	return "unknown", nil
+}
+
+// backupRDBOrAOF triggers a synchronous-ish snapshot (BGREWRITEAOF if AOF is
+// enabled, otherwise BGSAVE), waits for it to finish, and copies the
+// resulting file(s) into a directory next to archivePath. It mirrors the
+// usual "pick RDB or AOF based on persistence mode" branch in Redis backup
+// scripts, rather than reinventing a new persistence strategy.
+func backupRDBOrAOF(ctx context.Context, rdb *redis.Client, archivePath string) (string, error) {
+	aofEnabled, err := rdb.ConfigGet(ctx, "appendonly").Result()
+	if err != nil {
+		return "", fmt.Errorf("CONFIG GET appendonly: %w", err)
+	}
+
+	dir, err := configGetOne(ctx, rdb, "dir")
+	if err != nil {
+		return "", err
+	}
+
+	destDir := archivePath + ".persistence"
+	if err := os.MkdirAll(destDir, 0o755); err != nil {
+		return "", err
+	}
+
+	if aofEnabled["appendonly"] == "yes" {
+		if err := rdb.Do(ctx, "BGREWRITEAOF").Err(); err != nil {
+			return "", fmt.Errorf("BGREWRITEAOF: %w", err)
+		}
+		if err := waitPersistenceIdle(ctx, rdb, "aof_rewrite_in_progress"); err != nil {
+			return "", err
+		}
+		appendDirname, err := configGetOne(ctx, rdb, "appenddirname")
+		if err != nil {
+			return "", err
+		}
+		if err := copyDir(filepath.Join(dir, appendDirname), filepath.Join(destDir, appendDirname)); err != nil {
+			return "", err
+		}
+		return destDir, nil
+	}
+
+	if err := rdb.Do(ctx, "BGSAVE").Err(); err != nil {
+		return "", fmt.Errorf("BGSAVE: %w", err)
+	}
+	if err := waitPersistenceIdle(ctx, rdb, "rdb_bgsave_in_progress"); err != nil {
+		return "", err
+	}
+	dbFilename, err := configGetOne(ctx, rdb, "dbfilename")
+	if err != nil {
+		return "", err
+	}
+	src := filepath.Join(dir, dbFilename)
+	dst := filepath.Join(destDir, dbFilename)
+	if err := copyFile(src, dst); err != nil {
+		return "", err
+	}
+	return destDir, nil
+}
+
+func configGetOne(ctx context.Context, rdb *redis.Client, name string) (string, error) {
+	m, err := rdb.ConfigGet(ctx, name).Result()
+	if err != nil {
+		return "", fmt.Errorf("CONFIG GET %s: %w", name, err)
+	}
+	return m[name], nil
+}
+
+// waitPersistenceIdle polls INFO persistence until field reads "0", meaning
+// the in-progress BGSAVE/BGREWRITEAOF has finished.
+func waitPersistenceIdle(ctx context.Context, rdb *redis.Client, field string) error {
+	deadline := time.Now().Add(2 * time.Minute)
+	for time.Now().Before(deadline) {
+		info, err := rdb.Info(ctx, "persistence").Result()
+		if err != nil {
+			return fmt.Errorf("INFO persistence: %w", err)
+		}
+		for _, line := range strings.Split(info, "\r\n") {
+			if v, ok := strings.CutPrefix(line, field+":"); ok {
+				if v == "0" {
+					return nil
+				}
+			}
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+	return fmt.Errorf("timeout waiting for %s to finish", field)
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	_, err = io.Copy(out, in)
+	return err
+}
+
+func copyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(p string, d os.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0o755)
+		}
+		return copyFile(p, target)
+	})
+}
@@ -0,0 +1,425 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/redis-fs/mount/internal/client"
+	"golang.org/x/sys/unix"
+)
+
+// cmdExport is the inverse of cmdMigrate: it walks the tree stored under a
+// Redis key and reconstructs it as a plain directory on disk, using the
+// client package shared with the mount daemon rather than cmd/rfs's usual
+// ad hoc FS.* calls, since that's what lets it do chunked FS.READs.
+func cmdExport() error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	verify := fs.Bool("verify", false, "re-hash both sides after exporting and report any differences")
+	deleteExtra := fs.Bool("delete", false, "remove local paths under the target that don't exist in the Redis-backed FS")
+	if err := fs.Parse(os.Args[2:]); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: rfs export [flags] <target-dir>")
+	}
+	targetDir, err := expandPath(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	cfg, mountpoint, err := exportConnectionConfig()
+	if err != nil {
+		return err
+	}
+	if mountpoint != "" && pathsOverlap(targetDir, mountpoint) {
+		return fmt.Errorf("target directory %s overlaps the managed mountpoint %s", targetDir, mountpoint)
+	}
+
+	pingCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	rdb := connectRedis(cfg, 8)
+	defer rdb.Close()
+	if err := rdb.Ping(pingCtx).Err(); err != nil {
+		cancel()
+		return fmt.Errorf("cannot connect to Redis (%s): %w", cfg.redisSummary(), err)
+	}
+	cancel()
+
+	ctx := context.Background()
+	if err := ensureFSModuleLoaded(ctx, rdb); err != nil {
+		return err
+	}
+
+	c := client.New(rdb, cfg.RedisKey)
+
+	files, dirs, symlinks, seen, err := exportTree(ctx, c, targetDir)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Exported %d files, %d directories, %d symlinks to %s\n", files, dirs, symlinks, targetDir)
+
+	if *deleteExtra {
+		removed, err := pruneExtraneous(targetDir, seen)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Removed %d local path(s) not present in the Redis-backed FS\n", removed)
+	}
+
+	if *verify {
+		diffs, err := verifyExport(ctx, c, targetDir)
+		if err != nil {
+			return err
+		}
+		if len(diffs) == 0 {
+			fmt.Println("Verify: local copy matches the Redis-backed FS")
+			return nil
+		}
+		for _, d := range diffs {
+			fmt.Printf("  %s\n", d)
+		}
+		return fmt.Errorf("verify found %d mismatch(es) between %s and the Redis-backed FS", len(diffs), targetDir)
+	}
+	return nil
+}
+
+// exportConnectionConfig resolves how to connect for an export: reuse the
+// saved CLI state if one exists, otherwise fall back to the interactive
+// setup wizard for connection details. It also returns the managed
+// mountpoint (if any), so the caller can refuse an overlapping target.
+func exportConnectionConfig() (config, string, error) {
+	if st, err := loadState(); err == nil && st.RedisKey != "" {
+		fmt.Println("Using Redis connection from saved state")
+		return configFromState(st), st.Mountpoint, nil
+	}
+
+	fmt.Println("No saved state found; running setup wizard for connection details")
+	cfg, err := runWizard(os.Stdin, os.Stdout)
+	if err != nil {
+		return config{}, "", err
+	}
+	return cfg, cfg.Mountpoint, nil
+}
+
+// pathsOverlap reports whether a and b are the same directory or one is
+// nested inside the other.
+func pathsOverlap(a, b string) bool {
+	a = filepath.Clean(a)
+	b = filepath.Clean(b)
+	if a == b {
+		return true
+	}
+	return strings.HasPrefix(a, b+string(filepath.Separator)) || strings.HasPrefix(b, a+string(filepath.Separator))
+}
+
+// exportTree walks the Redis-backed FS rooted at "/" and recreates it under
+// targetDir. It returns the set of local paths it touched so a later
+// --delete pass can tell what's extraneous.
+func exportTree(ctx context.Context, c *client.Client, targetDir string) (files, dirs, symlinks int, seen map[string]struct{}, err error) {
+	if err := os.MkdirAll(targetDir, 0o755); err != nil {
+		return 0, 0, 0, nil, fmt.Errorf("create target dir: %w", err)
+	}
+	seen = make(map[string]struct{})
+
+	var walk func(redisDir string) error
+	walk = func(redisDir string) error {
+		names, err := c.Ls(ctx, redisDir)
+		if err != nil {
+			return fmt.Errorf("FS.LS %s: %w", redisDir, err)
+		}
+
+		redisPaths := make([]string, len(names))
+		for i, name := range names {
+			redisPaths[i] = path.Join(redisDir, name)
+		}
+		stats, err := batchStat(ctx, c, redisPaths)
+		if err != nil {
+			return fmt.Errorf("FS.STAT %s/*: %w", redisDir, err)
+		}
+
+		for i := range names {
+			redisPath := redisPaths[i]
+			localPath := localPathFor(targetDir, redisPath)
+
+			st := stats[i]
+			if st == nil {
+				continue // removed on the Redis side mid-walk
+			}
+
+			switch st.Type {
+			case "dir":
+				if err := os.MkdirAll(localPath, 0o755); err != nil {
+					return err
+				}
+				dirs++
+				seen[localPath] = struct{}{}
+				if err := walk(redisPath); err != nil {
+					return err
+				}
+			case "symlink":
+				target, err := c.Readlink(ctx, redisPath)
+				if err != nil {
+					return fmt.Errorf("FS.READLINK %s: %w", redisPath, err)
+				}
+				_ = os.Remove(localPath)
+				if err := os.Symlink(target, localPath); err != nil {
+					return fmt.Errorf("symlink %s: %w", localPath, err)
+				}
+				symlinks++
+				seen[localPath] = struct{}{}
+			default:
+				if err := exportFileChunked(ctx, c, redisPath, localPath, st.Size); err != nil {
+					return err
+				}
+				files++
+				seen[localPath] = struct{}{}
+			}
+
+			if err := applyLocalMetadata(localPath, st); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := walk("/"); err != nil {
+		return files, dirs, symlinks, seen, err
+	}
+	return files, dirs, symlinks, seen, nil
+}
+
+// localPathFor maps a Redis FS path (always "/"-rooted) to a path under
+// targetDir.
+func localPathFor(targetDir, redisPath string) string {
+	return filepath.Join(targetDir, filepath.FromSlash(strings.TrimPrefix(redisPath, "/")))
+}
+
+// batchStat resolves FS.STAT for every path in one Redis round trip instead
+// of one per path, so a directory with many entries doesn't pay its round
+// trip latency N times over. The result is positional: result[i] is the
+// FS.STAT of paths[i], nil if that path no longer exists.
+func batchStat(ctx context.Context, c *client.Client, paths []string) ([]*client.StatResult, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+
+	b := c.NewBatch(ctx)
+	futures := make([]*client.StatFuture, len(paths))
+	for i, p := range paths {
+		futures[i] = b.Stat(p)
+	}
+	if err := b.Exec(); err != nil {
+		return nil, err
+	}
+
+	results := make([]*client.StatResult, len(paths))
+	for i, f := range futures {
+		st, err := f.Result()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", paths[i], err)
+		}
+		results[i] = st
+	}
+	return results, nil
+}
+
+// exportFileChunked streams redisPath's content to localPath in
+// defaultImportChunkSize pieces via FS.READ, rather than loading the whole
+// file into memory the way FS.CAT would.
+func exportFileChunked(ctx context.Context, c *client.Client, redisPath, localPath string, size int64) error {
+	f, err := os.OpenFile(localPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("create %s: %w", localPath, err)
+	}
+	defer f.Close()
+
+	var offset int64
+	for offset < size {
+		length := int64(defaultImportChunkSize)
+		if remaining := size - offset; remaining < length {
+			length = remaining
+		}
+		data, err := c.Read(ctx, redisPath, offset, length)
+		if err != nil {
+			return fmt.Errorf("FS.READ %s at offset %d: %w", redisPath, offset, err)
+		}
+		if len(data) == 0 {
+			break // server reported more data than it actually has
+		}
+		if _, err := f.Write(data); err != nil {
+			return fmt.Errorf("write %s: %w", localPath, err)
+		}
+		offset += int64(len(data))
+	}
+	return nil
+}
+
+// applyLocalMetadata reapplies a Redis FS path's mode, ownership, and
+// timestamps to its exported local counterpart.
+func applyLocalMetadata(localPath string, st *client.StatResult) error {
+	if st.Type != "symlink" {
+		if err := os.Chmod(localPath, os.FileMode(st.Mode)); err != nil {
+			return fmt.Errorf("chmod %s: %w", localPath, err)
+		}
+	}
+	if err := os.Lchown(localPath, int(st.UID), int(st.GID)); err != nil {
+		return fmt.Errorf("lchown %s: %w", localPath, err)
+	}
+
+	ts := []unix.Timespec{
+		unix.NsecToTimespec(st.Atime * int64(time.Millisecond)),
+		unix.NsecToTimespec(st.Mtime * int64(time.Millisecond)),
+	}
+	if err := unix.UtimesNanoAt(unix.AT_FDCWD, localPath, ts, unix.AT_SYMLINK_NOFOLLOW); err != nil {
+		return fmt.Errorf("utimes %s: %w", localPath, err)
+	}
+	return nil
+}
+
+// pruneExtraneous removes local paths under targetDir that exportTree did
+// not touch, rsync --delete style.
+func pruneExtraneous(targetDir string, seen map[string]struct{}) (int, error) {
+	var toRemove []string
+	err := filepath.WalkDir(targetDir, func(p string, d os.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if p == targetDir {
+			return nil
+		}
+		if _, ok := seen[p]; ok {
+			return nil
+		}
+		toRemove = append(toRemove, p)
+		if d.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, p := range toRemove {
+		if err := os.RemoveAll(p); err != nil {
+			return removed, fmt.Errorf("remove %s: %w", p, err)
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// verifyExport re-hashes every regular file on both sides (and compares
+// symlink targets and directory presence) and returns one line per mismatch.
+func verifyExport(ctx context.Context, c *client.Client, targetDir string) ([]string, error) {
+	var diffs []string
+
+	var walk func(redisDir string) error
+	walk = func(redisDir string) error {
+		names, err := c.Ls(ctx, redisDir)
+		if err != nil {
+			return fmt.Errorf("FS.LS %s: %w", redisDir, err)
+		}
+
+		redisPaths := make([]string, len(names))
+		for i, name := range names {
+			redisPaths[i] = path.Join(redisDir, name)
+		}
+		stats, err := batchStat(ctx, c, redisPaths)
+		if err != nil {
+			return fmt.Errorf("FS.STAT %s/*: %w", redisDir, err)
+		}
+
+		for i := range names {
+			redisPath := redisPaths[i]
+			localPath := localPathFor(targetDir, redisPath)
+
+			st := stats[i]
+			if st == nil {
+				diffs = append(diffs, fmt.Sprintf("%s: present locally but no longer on the Redis-backed FS", redisPath))
+				continue
+			}
+
+			switch st.Type {
+			case "dir":
+				fi, statErr := os.Stat(localPath)
+				if statErr != nil || !fi.IsDir() {
+					diffs = append(diffs, fmt.Sprintf("%s: missing or not a directory locally", redisPath))
+					continue
+				}
+				if err := walk(redisPath); err != nil {
+					return err
+				}
+			case "symlink":
+				wantTarget, err := c.Readlink(ctx, redisPath)
+				if err != nil {
+					return fmt.Errorf("FS.READLINK %s: %w", redisPath, err)
+				}
+				gotTarget, statErr := os.Readlink(localPath)
+				if statErr != nil || gotTarget != wantTarget {
+					diffs = append(diffs, fmt.Sprintf("%s: symlink target mismatch (redis=%q local=%q)", redisPath, wantTarget, gotTarget))
+				}
+			default:
+				redisHash, err := hashRedisFile(ctx, c, redisPath, st.Size)
+				if err != nil {
+					return fmt.Errorf("hash %s: %w", redisPath, err)
+				}
+				localHash, hashErr := hashLocalFile(localPath)
+				if hashErr != nil || localHash != redisHash {
+					diffs = append(diffs, fmt.Sprintf("%s: content mismatch", redisPath))
+				}
+			}
+		}
+		return nil
+	}
+
+	if err := walk("/"); err != nil {
+		return nil, err
+	}
+	return diffs, nil
+}
+
+func hashRedisFile(ctx context.Context, c *client.Client, redisPath string, size int64) (string, error) {
+	h := sha256.New()
+	var offset int64
+	for offset < size {
+		length := int64(defaultImportChunkSize)
+		if remaining := size - offset; remaining < length {
+			length = remaining
+		}
+		data, err := c.Read(ctx, redisPath, offset, length)
+		if err != nil {
+			return "", err
+		}
+		if len(data) == 0 {
+			break
+		}
+		h.Write(data)
+		offset += int64(len(data))
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func hashLocalFile(localPath string) (string, error) {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
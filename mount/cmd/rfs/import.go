@@ -0,0 +1,486 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// defaultImportChunkSize is the size above which importDirectory streams a
+// file in chunks instead of loading it whole.
+const defaultImportChunkSize = 4 * 1024 * 1024
+
+// importProgressInterval controls how often importDirectory prints a
+// files/s, MB/s, ETA line while an import is running.
+const importProgressInterval = 2 * time.Second
+
+// importItemKind identifies what an importItem applies to. Directories are
+// never queued as an importItem: walkImportItems creates them synchronously
+// via importDirSync instead, so this only distinguishes the kinds that do go
+// through the worker pool.
+type importItemKind int
+
+const (
+	importSymlinkKind importItemKind = iota
+	importFileKind
+)
+
+// importItem is one path discovered by the walker, queued for a worker.
+type importItem struct {
+	redisPath string
+	fsPath    string
+	kind      importItemKind
+	info      os.FileInfo
+	target    string // symlink target, only set for importSymlinkKind
+}
+
+// importDirectory imports source into the Redis key at key, fanning the
+// walk out across workers goroutines. Regular files larger than chunkSize
+// are streamed in chunkSize pieces via repeated FS.ECHO/APPEND calls rather
+// than read into memory whole, and a sidecar progress file next to source
+// lets an interrupted import resume instead of restarting from scratch.
+func importDirectory(ctx context.Context, rdb redis.UniversalClient, key, source string, workers int, chunkSize int64) (files, dirs, symlinks int, err error) {
+	if workers <= 0 {
+		workers = 1
+	}
+	if chunkSize <= 0 {
+		chunkSize = defaultImportChunkSize
+	}
+
+	progress, err := newImportProgress(source)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("load import progress: %w", err)
+	}
+	if progress.completedCount() > 0 {
+		fmt.Printf("Resuming import: %d paths already complete\n", progress.completedCount())
+	}
+
+	totalFiles, totalBytes, err := preWalkImport(source)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("pre-walk %s: %w", source, err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	reporter := newImportReporter(totalFiles, totalBytes)
+	reporterDone := make(chan struct{})
+	go func() {
+		defer close(reporterDone)
+		reporter.run(ctx)
+	}()
+
+	items := make(chan importItem, workers*4)
+
+	var firstErr error
+	var errOnce sync.Once
+	recordErr := func(e error) {
+		if e == nil {
+			return
+		}
+		errOnce.Do(func() {
+			firstErr = e
+			cancel()
+		})
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			recordErr(runImportWorker(ctx, rdb, key, chunkSize, items, progress, reporter))
+		}()
+	}
+
+	recordErr(walkImportItems(ctx, rdb, key, source, items, progress, reporter))
+	close(items)
+	wg.Wait()
+
+	cancel()
+	<-reporterDone
+	reporter.printOnce()
+
+	files, dirs, symlinks = reporter.counts()
+	if firstErr != nil {
+		return files, dirs, symlinks, firstErr
+	}
+
+	if err := progress.remove(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return files, dirs, symlinks, fmt.Errorf("remove import progress file: %w", err)
+	}
+	return files, dirs, symlinks, nil
+}
+
+// preWalkImport sums the size and count of regular files under source so
+// importDirectory's progress reporter can compute an ETA.
+func preWalkImport(source string) (totalFiles int, totalBytes int64, err error) {
+	err = filepath.WalkDir(source, func(path string, d os.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if path == source || d.IsDir() || d.Type()&os.ModeSymlink != 0 {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		totalFiles++
+		totalBytes += info.Size()
+		return nil
+	})
+	return totalFiles, totalBytes, err
+}
+
+// walkImportItems walks source, creating each directory synchronously as
+// WalkDir reaches it (ahead of any worker that might need to write a file or
+// symlink under it), and feeds the file/symlink entries into items for the
+// worker pool to import concurrently. Directories are kept off the worker
+// pool entirely: WalkDir in a single goroutine guarantees parent-before-child
+// order, but the worker pool drains items concurrently, so a directory's own
+// FS.MKDIR racing against a sibling worker's FS.ECHO for a file inside it
+// would intermittently fail with "no such directory" depending on scheduling
+// if both went through the same queue.
+func walkImportItems(ctx context.Context, rdb redis.UniversalClient, key, source string, items chan<- importItem, progress *importProgress, reporter *importReporter) error {
+	return filepath.WalkDir(source, func(path string, d os.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if path == source {
+			return nil
+		}
+
+		rel, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		redisPath := "/" + filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			return importDirSync(ctx, rdb, key, redisPath, info, progress, reporter)
+		}
+
+		item := importItem{redisPath: redisPath, fsPath: path, info: info}
+		if d.Type()&os.ModeSymlink != 0 {
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			item.kind = importSymlinkKind
+			item.target = target
+		} else {
+			item.kind = importFileKind
+		}
+
+		select {
+		case items <- item:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
+
+// importDirSync creates a single directory and applies its metadata inline
+// in the walking goroutine, before WalkDir descends into it. This, not the
+// worker pool, is what makes a directory happen-before anything a worker
+// later writes inside it.
+func importDirSync(ctx context.Context, rdb redis.UniversalClient, key, redisPath string, info os.FileInfo, progress *importProgress, reporter *importReporter) error {
+	if progress.isDone(redisPath) {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := rdb.Do(ctx, "FS.MKDIR", key, redisPath, "PARENTS").Err(); err != nil {
+		return fmt.Errorf("FS.MKDIR %s: %w", redisPath, err)
+	}
+	if err := applyMetadataPipelined(ctx, rdb, key, redisPath, info); err != nil {
+		return err
+	}
+	reporter.addDir()
+	return progress.markDone(redisPath)
+}
+
+// runImportWorker drains items until the channel is closed or ctx is
+// cancelled, importing each one and checkpointing progress as it goes.
+func runImportWorker(ctx context.Context, rdb redis.UniversalClient, key string, chunkSize int64, items <-chan importItem, progress *importProgress, reporter *importReporter) error {
+	for item := range items {
+		if progress.isDone(item.redisPath) {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		switch item.kind {
+		case importSymlinkKind:
+			if err := rdb.Do(ctx, "FS.LN", key, item.target, item.redisPath).Err(); err != nil {
+				return fmt.Errorf("FS.LN %s: %w", item.redisPath, err)
+			}
+			if err := applyMetadataPipelined(ctx, rdb, key, item.redisPath, item.info); err != nil {
+				return err
+			}
+			reporter.addSymlink()
+		case importFileKind:
+			if err := importFileChunked(ctx, rdb, key, chunkSize, item, progress, reporter); err != nil {
+				return err
+			}
+			if err := applyMetadataPipelined(ctx, rdb, key, item.redisPath, item.info); err != nil {
+				return err
+			}
+			reporter.addFile()
+		}
+
+		if err := progress.markDone(item.redisPath); err != nil {
+			return fmt.Errorf("checkpoint %s: %w", item.redisPath, err)
+		}
+	}
+	return nil
+}
+
+// importFileChunked writes a regular file's content to Redis. Files at or
+// under chunkSize go through a single FS.ECHO; larger files are streamed in
+// chunkSize pieces (the first plain FS.ECHO, the rest FS.ECHO ... APPEND),
+// checkpointing (path, offset) after every chunk so a later resume can pick
+// up an in-flight file where it left off.
+func importFileChunked(ctx context.Context, rdb redis.UniversalClient, key string, chunkSize int64, item importItem, progress *importProgress, reporter *importReporter) error {
+	f, err := os.Open(item.fsPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if item.info.Size() <= chunkSize {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return err
+		}
+		if err := rdb.Do(ctx, "FS.ECHO", key, item.redisPath, data).Err(); err != nil {
+			return fmt.Errorf("FS.ECHO %s: %w", item.redisPath, err)
+		}
+		reporter.addBytes(int64(len(data)))
+		return nil
+	}
+
+	offset := progress.resumeOffset(item.redisPath)
+	if offset > 0 {
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			return err
+		}
+	}
+
+	buf := make([]byte, chunkSize)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n, readErr := f.Read(buf)
+		if n > 0 {
+			chunk := buf[:n]
+			var writeErr error
+			if offset == 0 {
+				writeErr = rdb.Do(ctx, "FS.ECHO", key, item.redisPath, chunk).Err()
+			} else {
+				writeErr = rdb.Do(ctx, "FS.ECHO", key, item.redisPath, chunk, "APPEND").Err()
+			}
+			if writeErr != nil {
+				return fmt.Errorf("FS.ECHO %s at offset %d: %w", item.redisPath, offset, writeErr)
+			}
+			offset += int64(n)
+			if err := progress.markChunk(item.redisPath, offset); err != nil {
+				return fmt.Errorf("checkpoint %s at offset %d: %w", item.redisPath, offset, err)
+			}
+			reporter.addBytes(int64(n))
+		}
+		if readErr == io.EOF {
+			return nil
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+}
+
+// applyMetadataPipelined sets permissions, ownership, and timestamps for
+// path in a single round trip via a Redis pipeline.
+func applyMetadataPipelined(ctx context.Context, rdb redis.UniversalClient, key, path string, info os.FileInfo) error {
+	modeStr := fmt.Sprintf("%04o", info.Mode().Perm())
+	st, hasStat := info.Sys().(*syscall.Stat_t)
+
+	_, err := rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Do(ctx, "FS.CHMOD", key, path, modeStr)
+		if hasStat {
+			pipe.Do(ctx, "FS.CHOWN", key, path, st.Uid, st.Gid)
+			atimeMs := st.Atim.Sec*1000 + st.Atim.Nsec/1_000_000
+			mtimeMs := st.Mtim.Sec*1000 + st.Mtim.Nsec/1_000_000
+			pipe.Do(ctx, "FS.UTIMENS", key, path, atimeMs, mtimeMs)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("apply metadata %s: %w", path, err)
+	}
+	return nil
+}
+
+// importProgressPath is the sidecar checkpoint file for an import of source.
+func importProgressPath(source string) string {
+	return source + ".rfs-import.progress"
+}
+
+// importProgress tracks which paths an import has fully written and, for a
+// file still in progress, how many bytes of it have landed in Redis so far.
+// It is safe for concurrent use by the worker pool and persists itself to
+// importProgressPath(source) after every update so a later run can resume.
+type importProgress struct {
+	mu   sync.Mutex
+	path string
+
+	Completed map[string]bool  `json:"completed"`
+	Partial   map[string]int64 `json:"partial"`
+}
+
+func newImportProgress(source string) (*importProgress, error) {
+	p := &importProgress{path: importProgressPath(source), Completed: map[string]bool{}, Partial: map[string]int64{}}
+
+	b, err := os.ReadFile(p.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return p, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(b, p); err != nil {
+		return nil, err
+	}
+	if p.Completed == nil {
+		p.Completed = map[string]bool{}
+	}
+	if p.Partial == nil {
+		p.Partial = map[string]int64{}
+	}
+	return p, nil
+}
+
+func (p *importProgress) isDone(redisPath string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.Completed[redisPath]
+}
+
+func (p *importProgress) resumeOffset(redisPath string) int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.Partial[redisPath]
+}
+
+func (p *importProgress) completedCount() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.Completed)
+}
+
+func (p *importProgress) markChunk(redisPath string, offset int64) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.Partial[redisPath] = offset
+	return p.saveLocked()
+}
+
+func (p *importProgress) markDone(redisPath string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.Partial, redisPath)
+	p.Completed[redisPath] = true
+	return p.saveLocked()
+}
+
+func (p *importProgress) saveLocked() error {
+	b, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(p.path, b, 0o644)
+}
+
+func (p *importProgress) remove() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return os.Remove(p.path)
+}
+
+// importReporter accumulates import progress and periodically prints a
+// files/s, MB/s, ETA summary computed against preWalkImport's totals.
+type importReporter struct {
+	startedAt  time.Time
+	totalFiles int64
+	totalBytes int64
+
+	filesDone    atomic.Int64
+	dirsDone     atomic.Int64
+	symlinksDone atomic.Int64
+	bytesDone    atomic.Int64
+}
+
+func newImportReporter(totalFiles int, totalBytes int64) *importReporter {
+	return &importReporter{startedAt: time.Now(), totalFiles: int64(totalFiles), totalBytes: totalBytes}
+}
+
+func (r *importReporter) addFile()         { r.filesDone.Add(1) }
+func (r *importReporter) addDir()          { r.dirsDone.Add(1) }
+func (r *importReporter) addSymlink()      { r.symlinksDone.Add(1) }
+func (r *importReporter) addBytes(n int64) { r.bytesDone.Add(n) }
+
+func (r *importReporter) counts() (files, dirs, symlinks int) {
+	return int(r.filesDone.Load()), int(r.dirsDone.Load()), int(r.symlinksDone.Load())
+}
+
+// run prints a progress line on importProgressInterval until ctx is done.
+func (r *importReporter) run(ctx context.Context) {
+	ticker := time.NewTicker(importProgressInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.printOnce()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (r *importReporter) printOnce() {
+	elapsed := time.Since(r.startedAt).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+	filesDone := r.filesDone.Load()
+	bytesDone := r.bytesDone.Load()
+	filesPerSec := float64(filesDone) / elapsed
+	mbPerSec := float64(bytesDone) / elapsed / (1024 * 1024)
+
+	eta := "unknown"
+	if bytesDone > 0 && r.totalBytes > bytesDone {
+		remaining := r.totalBytes - bytesDone
+		secsLeft := float64(remaining) / (float64(bytesDone) / elapsed)
+		eta = time.Duration(secsLeft * float64(time.Second)).Round(time.Second).String()
+	}
+
+	fmt.Printf("import: %d/%d files, %.1f files/s, %.1f MB/s, ETA %s\n", filesDone, r.totalFiles, filesPerSec, mbPerSec, eta)
+}
@@ -5,11 +5,13 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strconv"
 	"strings"
 	"syscall"
@@ -18,12 +20,25 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+// Redis topologies a managed mount can connect to. "standalone" is a single
+// Redis server; "sentinel" fails over between nodes managed by Sentinel;
+// "cluster" shards across a Redis Cluster.
+const (
+	topologyStandalone = "standalone"
+	topologySentinel   = "sentinel"
+	topologyCluster    = "cluster"
+)
+
 type state struct {
 	StartedAt      time.Time `json:"started_at"`
 	ManageRedis    bool      `json:"manage_redis"`
 	RedisPID       int       `json:"redis_pid"`
+	RedisMode      string    `json:"redis_mode,omitempty"` // "", "sentinel", or "cluster"; empty means standalone
 	RedisAddr      string    `json:"redis_addr"`
 	RedisDB        int       `json:"redis_db"`
+	SentinelMaster string    `json:"sentinel_master,omitempty"`
+	SentinelAddrs  []string  `json:"sentinel_addrs,omitempty"`
+	ClusterAddrs   []string  `json:"cluster_addrs,omitempty"`
 	MountPID       int       `json:"mount_pid"`
 	Mountpoint     string    `json:"mountpoint"`
 	RedisKey       string    `json:"redis_key"`
@@ -31,6 +46,8 @@ type state struct {
 	MountLog       string    `json:"mount_log"`
 	RedisServerBin string    `json:"redis_server_bin"`
 	MountBin       string    `json:"mount_bin"`
+	ReadOnly       bool      `json:"read_only,omitempty"`
+	AllowOther     bool      `json:"allow_other,omitempty"`
 	ArchivePath    string    `json:"archive_path,omitempty"`
 }
 
@@ -38,18 +55,91 @@ type config struct {
 	UseExistingRedis bool
 	RedisServerBin   string
 	ModulePath       string
-	RedisAddr        string
-	RedisHost        string
-	RedisPort        int
-	RedisPassword    string
-	RedisDB          int
-	RedisKey         string
-	Mountpoint       string
-	MountBin         string
-	ReadOnly         bool
-	AllowOther       bool
-	RedisLog         string
-	MountLog         string
+
+	RedisMode     string // topologyStandalone, topologySentinel, or topologyCluster
+	RedisAddr     string
+	RedisHost     string
+	RedisPort     int
+	RedisPassword string
+	RedisDB       int
+
+	SentinelMaster   string
+	SentinelAddrs    []string
+	SentinelPassword string
+
+	ClusterAddrs []string
+
+	RedisKey   string
+	Mountpoint string
+	MountBin   string
+	ReadOnly   bool
+	AllowOther bool
+	RedisLog   string
+	MountLog   string
+}
+
+// redisSummary is a short human-readable description of cfg's Redis
+// topology, used for status output and log lines.
+func (cfg config) redisSummary() string {
+	switch cfg.RedisMode {
+	case topologySentinel:
+		return fmt.Sprintf("sentinel master=%s via %s", cfg.SentinelMaster, strings.Join(cfg.SentinelAddrs, ","))
+	case topologyCluster:
+		return fmt.Sprintf("cluster seeds=%s", strings.Join(cfg.ClusterAddrs, ","))
+	default:
+		return fmt.Sprintf("%s (db %d)", cfg.RedisAddr, cfg.RedisDB)
+	}
+}
+
+// stateFromConfig copies cfg's Redis connection and daemon-binary fields
+// into a fresh state, leaving the run-specific fields (StartedAt, pids,
+// Mountpoint, ArchivePath) for the caller to fill in.
+func stateFromConfig(cfg config) state {
+	return state{
+		RedisMode:      cfg.RedisMode,
+		RedisAddr:      cfg.RedisAddr,
+		RedisDB:        cfg.RedisDB,
+		SentinelMaster: cfg.SentinelMaster,
+		SentinelAddrs:  cfg.SentinelAddrs,
+		ClusterAddrs:   cfg.ClusterAddrs,
+		RedisKey:       cfg.RedisKey,
+		RedisLog:       cfg.RedisLog,
+		MountLog:       cfg.MountLog,
+		RedisServerBin: cfg.RedisServerBin,
+		MountBin:       cfg.MountBin,
+		ReadOnly:       cfg.ReadOnly,
+		AllowOther:     cfg.AllowOther,
+	}
+}
+
+// connectRedis builds a redis.UniversalClient for cfg's topology: a plain
+// standalone client, a Sentinel-managed failover client, or a Cluster
+// client, chosen the same way redis-fs-mount's connectRedis does.
+func connectRedis(cfg config, poolSize int) redis.UniversalClient {
+	switch cfg.RedisMode {
+	case topologySentinel:
+		return redis.NewUniversalClient(&redis.UniversalOptions{
+			MasterName:       cfg.SentinelMaster,
+			Addrs:            cfg.SentinelAddrs,
+			SentinelPassword: cfg.SentinelPassword,
+			Password:         cfg.RedisPassword,
+			DB:               cfg.RedisDB,
+			PoolSize:         poolSize,
+		})
+	case topologyCluster:
+		return redis.NewUniversalClient(&redis.UniversalOptions{
+			Addrs:    cfg.ClusterAddrs,
+			Password: cfg.RedisPassword,
+			PoolSize: poolSize,
+		})
+	default:
+		return redis.NewClient(&redis.Options{
+			Addr:     cfg.RedisAddr,
+			Password: cfg.RedisPassword,
+			DB:       cfg.RedisDB,
+			PoolSize: poolSize,
+		})
+	}
 }
 
 func main() {
@@ -75,6 +165,22 @@ func main() {
 		if err := cmdDown(); err != nil {
 			fatal(err)
 		}
+	case "backup":
+		if err := cmdBackup(); err != nil {
+			fatal(err)
+		}
+	case "restore":
+		if err := cmdRestore(); err != nil {
+			fatal(err)
+		}
+	case "supervise":
+		if err := cmdSupervise(); err != nil {
+			fatal(err)
+		}
+	case "export":
+		if err := cmdExport(); err != nil {
+			fatal(err)
+		}
 	default:
 		printUsage()
 		os.Exit(1)
@@ -82,16 +188,28 @@ func main() {
 }
 
 func printUsage() {
-	fmt.Fprintf(os.Stderr, "Usage: %s <up|migrate|status|down>\n", filepath.Base(os.Args[0]))
+	fmt.Fprintf(os.Stderr, "Usage: %s <up|migrate|status|down|backup|restore|supervise|export>\n", filepath.Base(os.Args[0]))
 	fmt.Fprintln(os.Stderr)
 	fmt.Fprintln(os.Stderr, "Commands:")
-	fmt.Fprintln(os.Stderr, "  up      Interactive wizard to start Redis + mount daemons")
-	fmt.Fprintln(os.Stderr, "  migrate Import a local directory, archive it, then mount Redis in place")
-	fmt.Fprintln(os.Stderr, "  status  Show status for managed daemons and mount")
-	fmt.Fprintln(os.Stderr, "  down    Stop managed daemons and unmount")
+	fmt.Fprintln(os.Stderr, "  up         Interactive wizard to start Redis + mount daemons (--supervise to stay in the foreground and auto-restart)")
+	fmt.Fprintln(os.Stderr, "  migrate    Import a local directory, archive it, then mount Redis in place")
+	fmt.Fprintln(os.Stderr, "  status     Show status for managed daemons and mount")
+	fmt.Fprintln(os.Stderr, "  down       Stop managed daemons and unmount")
+	fmt.Fprintln(os.Stderr, "  backup     Snapshot a redis-fs key to a .rfs.tar.gz archive")
+	fmt.Fprintln(os.Stderr, "  restore    Ingest a .rfs.tar.gz archive back into a redis-fs key")
+	fmt.Fprintln(os.Stderr, "  supervise  Health-check an already-running managed mount, restarting it on failure")
+	fmt.Fprintln(os.Stderr, "  export     Materialize a redis-fs key back to a plain local directory")
 }
 
 func cmdUp() error {
+	fs := flag.NewFlagSet("up", flag.ExitOnError)
+	supervise := fs.Bool("supervise", false, "after starting daemons, stay in the foreground running the health-check supervisor")
+	superviseInterval := fs.Duration("supervise-interval", defaultSuperviseInterval, "health-check interval, with --supervise")
+	maxRestarts := fs.Int("max-restarts", defaultSuperviseMaxRestart, "give up supervising after this many consecutive restarts, with --supervise (0 = unlimited)")
+	if err := fs.Parse(os.Args[2:]); err != nil {
+		return err
+	}
+
 	if st, err := loadState(); err == nil {
 		if st.MountPID > 0 && processAlive(st.MountPID) {
 			return fmt.Errorf("an existing managed mount process is running (pid %d). Run '%s down' first", st.MountPID, filepath.Base(os.Args[0]))
@@ -116,16 +234,11 @@ func cmdUp() error {
 		fmt.Printf("Started Redis daemon (pid %d) at %s\n", pid, cfg.RedisAddr)
 	}
 
-	rdb := redis.NewClient(&redis.Options{
-		Addr:     cfg.RedisAddr,
-		Password: cfg.RedisPassword,
-		DB:       cfg.RedisDB,
-		PoolSize: 4,
-	})
+	rdb := connectRedis(cfg, 4)
 	defer rdb.Close()
 
 	if err := rdb.Ping(ctx).Err(); err != nil {
-		return fmt.Errorf("cannot connect to Redis at %s: %w", cfg.RedisAddr, err)
+		return fmt.Errorf("cannot connect to Redis (%s): %w", cfg.redisSummary(), err)
 	}
 
 	if err := ensureFSModuleLoaded(ctx, rdb); err != nil {
@@ -150,19 +263,11 @@ func cmdUp() error {
 		return fmt.Errorf("mount did not become ready: %w", err)
 	}
 
-	st := state{
-		StartedAt:      time.Now().UTC(),
-		ManageRedis:    !cfg.UseExistingRedis,
-		RedisAddr:      cfg.RedisAddr,
-		RedisDB:        cfg.RedisDB,
-		MountPID:       mpid,
-		Mountpoint:     cfg.Mountpoint,
-		RedisKey:       cfg.RedisKey,
-		RedisLog:       cfg.RedisLog,
-		MountLog:       cfg.MountLog,
-		RedisServerBin: cfg.RedisServerBin,
-		MountBin:       cfg.MountBin,
-	}
+	st := stateFromConfig(cfg)
+	st.StartedAt = time.Now().UTC()
+	st.ManageRedis = !cfg.UseExistingRedis
+	st.MountPID = mpid
+	st.Mountpoint = cfg.Mountpoint
 	if !cfg.UseExistingRedis {
 		st.RedisPID = redisPID
 	}
@@ -178,6 +283,11 @@ func cmdUp() error {
 	if st.ManageRedis {
 		fmt.Printf("Redis log:  %s\n", cfg.RedisLog)
 	}
+
+	if *supervise {
+		fmt.Println("Entering supervise mode (Ctrl+C to stop supervising; the mount stays up).")
+		return superviseLoop(cfg, &st, *superviseInterval, *maxRestarts)
+	}
 	return nil
 }
 
@@ -192,7 +302,14 @@ func cmdStatus() error {
 	}
 
 	fmt.Printf("Started at: %s\n", st.StartedAt.Format(time.RFC3339))
-	fmt.Printf("Redis addr: %s (db %d)\n", st.RedisAddr, st.RedisDB)
+	switch st.RedisMode {
+	case topologySentinel:
+		fmt.Printf("Redis:      sentinel master=%s via %s\n", st.SentinelMaster, strings.Join(st.SentinelAddrs, ","))
+	case topologyCluster:
+		fmt.Printf("Redis:      cluster seeds=%s\n", strings.Join(st.ClusterAddrs, ","))
+	default:
+		fmt.Printf("Redis addr: %s (db %d)\n", st.RedisAddr, st.RedisDB)
+	}
 	fmt.Printf("Redis key:  %s\n", st.RedisKey)
 	fmt.Printf("Mountpoint: %s\n", st.Mountpoint)
 
@@ -232,6 +349,13 @@ func cmdStatus() error {
 }
 
 func cmdMigrate() error {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	workers := fs.Int("workers", runtime.NumCPU(), "number of parallel import workers")
+	chunkSize := fs.Int64("chunk-size", defaultImportChunkSize, "stream files larger than this many bytes in chunks instead of loading them whole")
+	if err := fs.Parse(os.Args[2:]); err != nil {
+		return err
+	}
+
 	if st, err := loadState(); err == nil {
 		if st.MountPID > 0 && processAlive(st.MountPID) {
 			return fmt.Errorf("an existing managed mount process is running (pid %d). Run '%s down' first", st.MountPID, filepath.Base(os.Args[0]))
@@ -256,16 +380,11 @@ func cmdMigrate() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
 
-	rdb := redis.NewClient(&redis.Options{
-		Addr:     cfg.RedisAddr,
-		Password: cfg.RedisPassword,
-		DB:       cfg.RedisDB,
-		PoolSize: 8,
-	})
+	rdb := connectRedis(cfg, 8)
 	defer rdb.Close()
 
 	if err := rdb.Ping(ctx).Err(); err != nil {
-		return fmt.Errorf("cannot connect to Redis at %s: %w", cfg.RedisAddr, err)
+		return fmt.Errorf("cannot connect to Redis (%s): %w", cfg.redisSummary(), err)
 	}
 	if err := ensureFSModuleLoaded(ctx, rdb); err != nil {
 		return err
@@ -288,7 +407,9 @@ func cmdMigrate() error {
 		}
 	}
 
-	files, dirs, links, err := importDirectory(ctx, rdb, cfg.RedisKey, sourceDir)
+	// Importing a large tree can run far longer than the 60s connection-setup
+	// budget above, so it gets its own unbounded context.
+	files, dirs, links, err := importDirectory(context.Background(), rdb, cfg.RedisKey, sourceDir, *workers, *chunkSize)
 	if err != nil {
 		return err
 	}
@@ -327,21 +448,13 @@ func cmdMigrate() error {
 		return fmt.Errorf("mount did not become ready: %w", err)
 	}
 
-	st := state{
-		StartedAt:      time.Now().UTC(),
-		ManageRedis:    !cfg.UseExistingRedis,
-		RedisPID:       redisPID,
-		RedisAddr:      cfg.RedisAddr,
-		RedisDB:        cfg.RedisDB,
-		MountPID:       mpid,
-		Mountpoint:     cfg.Mountpoint,
-		RedisKey:       cfg.RedisKey,
-		RedisLog:       cfg.RedisLog,
-		MountLog:       cfg.MountLog,
-		RedisServerBin: cfg.RedisServerBin,
-		MountBin:       cfg.MountBin,
-		ArchivePath:    archiveDir,
-	}
+	st := stateFromConfig(cfg)
+	st.StartedAt = time.Now().UTC()
+	st.ManageRedis = !cfg.UseExistingRedis
+	st.RedisPID = redisPID
+	st.MountPID = mpid
+	st.Mountpoint = cfg.Mountpoint
+	st.ArchivePath = archiveDir
 	if err := saveState(st); err != nil {
 		return err
 	}
@@ -411,70 +524,135 @@ func runWizardWithReader(r *bufio.Reader, out io.Writer, defaultMount string, pr
 	fmt.Fprintln(out, "Redis-FS CLI setup")
 	fmt.Fprintln(out, "------------------")
 
-	useExisting, err := promptYesNo(r, out, "Use an existing Redis instance?", true)
+	mode, err := promptString(r, out, "Redis topology (standalone/sentinel/cluster)", topologyStandalone)
 	if err != nil {
 		return cfg, err
 	}
-	cfg.UseExistingRedis = useExisting
+	cfg.RedisMode = strings.ToLower(strings.TrimSpace(mode))
 
-	addr, err := promptString(r, out, "Redis address (host:port)", cfg.RedisAddr)
-	if err != nil {
-		return cfg, err
-	}
-	cfg.RedisAddr = addr
+	switch cfg.RedisMode {
+	case topologySentinel:
+		cfg.UseExistingRedis = true
 
-	host, port, err := splitAddr(cfg.RedisAddr)
-	if err != nil {
-		return cfg, err
-	}
-	cfg.RedisHost = host
-	cfg.RedisPort = port
+		master, err := promptString(r, out, "Sentinel master name", "mymaster")
+		if err != nil {
+			return cfg, err
+		}
+		cfg.SentinelMaster = master
 
-	pwd, err := promptString(r, out, "Redis password (empty for none)", "")
-	if err != nil {
-		return cfg, err
-	}
-	cfg.RedisPassword = pwd
+		addrs, err := promptString(r, out, "Sentinel addresses (comma-separated host:port)", "")
+		if err != nil {
+			return cfg, err
+		}
+		cfg.SentinelAddrs = parseAddrList(addrs)
+		if len(cfg.SentinelAddrs) == 0 {
+			return cfg, errors.New("at least one sentinel address is required")
+		}
 
-	db, err := promptInt(r, out, "Redis DB number", cfg.RedisDB)
-	if err != nil {
-		return cfg, err
-	}
-	cfg.RedisDB = db
+		sentinelPwd, err := promptString(r, out, "Sentinel password (empty for none)", "")
+		if err != nil {
+			return cfg, err
+		}
+		cfg.SentinelPassword = sentinelPwd
 
-	if !cfg.UseExistingRedis {
-		redisBin, err := promptString(r, out, "Path to redis-server binary", defRedisBin)
+		pwd, err := promptString(r, out, "Redis password (empty for none)", "")
 		if err != nil {
 			return cfg, err
 		}
-		cfg.RedisServerBin, err = resolveBinary(redisBin)
+		cfg.RedisPassword = pwd
+
+		db, err := promptInt(r, out, "Redis DB number", cfg.RedisDB)
 		if err != nil {
 			return cfg, err
 		}
-		if _, err := os.Stat(cfg.RedisServerBin); err != nil {
-			return cfg, fmt.Errorf("redis-server not found at %s", cfg.RedisServerBin)
+		cfg.RedisDB = db
+
+	case topologyCluster:
+		cfg.UseExistingRedis = true
+
+		addrs, err := promptString(r, out, "Cluster seed addresses (comma-separated host:port)", "")
+		if err != nil {
+			return cfg, err
+		}
+		cfg.ClusterAddrs = parseAddrList(addrs)
+		if len(cfg.ClusterAddrs) == 0 {
+			return cfg, errors.New("at least one cluster seed address is required")
+		}
+
+		pwd, err := promptString(r, out, "Redis password (empty for none)", "")
+		if err != nil {
+			return cfg, err
 		}
+		cfg.RedisPassword = pwd
+
+	default:
+		cfg.RedisMode = topologyStandalone
 
-		modulePath, err := promptString(r, out, "Path to module fs.so", defModulePath)
+		useExisting, err := promptYesNo(r, out, "Use an existing Redis instance?", true)
 		if err != nil {
 			return cfg, err
 		}
-		cfg.ModulePath, err = expandPath(modulePath)
+		cfg.UseExistingRedis = useExisting
+
+		addr, err := promptString(r, out, "Redis address (host:port)", cfg.RedisAddr)
 		if err != nil {
 			return cfg, err
 		}
-		if _, err := os.Stat(cfg.ModulePath); err != nil {
-			return cfg, fmt.Errorf("module not found at %s", cfg.ModulePath)
+		cfg.RedisAddr = addr
+
+		host, port, err := splitAddr(cfg.RedisAddr)
+		if err != nil {
+			return cfg, err
 		}
+		cfg.RedisHost = host
+		cfg.RedisPort = port
 
-		redisLog, err := promptString(r, out, "Redis log file", cfg.RedisLog)
+		pwd, err := promptString(r, out, "Redis password (empty for none)", "")
 		if err != nil {
 			return cfg, err
 		}
-		cfg.RedisLog, err = expandPath(redisLog)
+		cfg.RedisPassword = pwd
+
+		db, err := promptInt(r, out, "Redis DB number", cfg.RedisDB)
 		if err != nil {
 			return cfg, err
 		}
+		cfg.RedisDB = db
+
+		if !cfg.UseExistingRedis {
+			redisBin, err := promptString(r, out, "Path to redis-server binary", defRedisBin)
+			if err != nil {
+				return cfg, err
+			}
+			cfg.RedisServerBin, err = resolveBinary(redisBin)
+			if err != nil {
+				return cfg, err
+			}
+			if _, err := os.Stat(cfg.RedisServerBin); err != nil {
+				return cfg, fmt.Errorf("redis-server not found at %s", cfg.RedisServerBin)
+			}
+
+			modulePath, err := promptString(r, out, "Path to module fs.so", defModulePath)
+			if err != nil {
+				return cfg, err
+			}
+			cfg.ModulePath, err = expandPath(modulePath)
+			if err != nil {
+				return cfg, err
+			}
+			if _, err := os.Stat(cfg.ModulePath); err != nil {
+				return cfg, fmt.Errorf("module not found at %s", cfg.ModulePath)
+			}
+
+			redisLog, err := promptString(r, out, "Redis log file", cfg.RedisLog)
+			if err != nil {
+				return cfg, err
+			}
+			cfg.RedisLog, err = expandPath(redisLog)
+			if err != nil {
+				return cfg, err
+			}
+		}
 	}
 
 	mountBin, err := promptString(r, out, "Path to redis-fs-mount binary", defMountBin)
@@ -581,79 +759,7 @@ func runMigrationWizard(in io.Reader, out io.Writer) (config, string, string, er
 	return cfg, source, archiveDir, nil
 }
 
-func importDirectory(ctx context.Context, rdb *redis.Client, key, source string) (files int, dirs int, symlinks int, err error) {
-	err = filepath.WalkDir(source, func(path string, d os.DirEntry, walkErr error) error {
-		if walkErr != nil {
-			return walkErr
-		}
-		if path == source {
-			return nil
-		}
-
-		rel, err := filepath.Rel(source, path)
-		if err != nil {
-			return err
-		}
-		redisPath := "/" + filepath.ToSlash(rel)
-
-		info, err := os.Lstat(path)
-		if err != nil {
-			return err
-		}
-
-		switch {
-		case d.Type()&os.ModeSymlink != 0:
-			target, err := os.Readlink(path)
-			if err != nil {
-				return err
-			}
-			if err := rdb.Do(ctx, "FS.LN", key, target, redisPath).Err(); err != nil {
-				return fmt.Errorf("FS.LN %s: %w", redisPath, err)
-			}
-			symlinks++
-		case d.IsDir():
-			if err := rdb.Do(ctx, "FS.MKDIR", key, redisPath, "PARENTS").Err(); err != nil {
-				return fmt.Errorf("FS.MKDIR %s: %w", redisPath, err)
-			}
-			dirs++
-		default:
-			data, err := os.ReadFile(path)
-			if err != nil {
-				return err
-			}
-			if err := rdb.Do(ctx, "FS.ECHO", key, redisPath, data).Err(); err != nil {
-				return fmt.Errorf("FS.ECHO %s: %w", redisPath, err)
-			}
-			files++
-		}
-
-		if err := applyMetadata(ctx, rdb, key, redisPath, info); err != nil {
-			return err
-		}
-		return nil
-	})
-	return files, dirs, symlinks, err
-}
-
-func applyMetadata(ctx context.Context, rdb *redis.Client, key, path string, info os.FileInfo) error {
-	modeStr := fmt.Sprintf("%04o", info.Mode().Perm())
-	if err := rdb.Do(ctx, "FS.CHMOD", key, path, modeStr).Err(); err != nil {
-		return fmt.Errorf("FS.CHMOD %s: %w", path, err)
-	}
-
-	if st, ok := info.Sys().(*syscall.Stat_t); ok {
-		if err := rdb.Do(ctx, "FS.CHOWN", key, path, st.Uid, st.Gid).Err(); err != nil {
-			return fmt.Errorf("FS.CHOWN %s: %w", path, err)
-		}
-
-		atimeMs := st.Atim.Sec*1000 + st.Atim.Nsec/1_000_000
-		mtimeMs := st.Mtim.Sec*1000 + st.Mtim.Nsec/1_000_000
-		if err := rdb.Do(ctx, "FS.UTIMENS", key, path, atimeMs, mtimeMs).Err(); err != nil {
-			return fmt.Errorf("FS.UTIMENS %s: %w", path, err)
-		}
-	}
-	return nil
-}
+// importDirectory and its supporting pipeline live in import.go.
 
 func startRedisDaemon(cfg config) (int, error) {
 	pidfile := fmt.Sprintf("/tmp/rfs-%d.pid", cfg.RedisPort)
@@ -698,12 +804,25 @@ func startMountDaemon(cfg config) (int, error) {
 	}
 
 	args := []string{
-		"--redis", cfg.RedisAddr,
 		"--db", strconv.Itoa(cfg.RedisDB),
 		"--foreground",
 		cfg.RedisKey,
 		cfg.Mountpoint,
 	}
+	switch cfg.RedisMode {
+	case topologySentinel:
+		args = append([]string{
+			"--sentinel-master", cfg.SentinelMaster,
+			"--sentinel-addrs", strings.Join(cfg.SentinelAddrs, ","),
+		}, args...)
+		if cfg.SentinelPassword != "" {
+			args = append([]string{"--sentinel-password", cfg.SentinelPassword}, args...)
+		}
+	case topologyCluster:
+		args = append([]string{"--cluster-addrs", strings.Join(cfg.ClusterAddrs, ",")}, args...)
+	default:
+		args = append([]string{"--redis", cfg.RedisAddr}, args...)
+	}
 	if cfg.RedisPassword != "" {
 		args = append([]string{"--password", cfg.RedisPassword}, args...)
 	}
@@ -734,7 +853,24 @@ func startMountDaemon(cfg config) (int, error) {
 	return pid, nil
 }
 
-func ensureFSModuleLoaded(ctx context.Context, rdb *redis.Client) error {
+// ensureFSModuleLoaded checks that the 'fs' module is loaded. In cluster
+// mode the module is a per-node install, so a module missing from any
+// master is fatal even if other masters have it.
+func ensureFSModuleLoaded(ctx context.Context, rdb redis.UniversalClient) error {
+	if cc, ok := rdb.(*redis.ClusterClient); ok {
+		return cc.ForEachMaster(ctx, func(ctx context.Context, shard *redis.Client) error {
+			if err := checkFSModuleLoaded(ctx, shard); err != nil {
+				return fmt.Errorf("node %s: %w", shard.Options().Addr, err)
+			}
+			return nil
+		})
+	}
+	return checkFSModuleLoaded(ctx, rdb)
+}
+
+func checkFSModuleLoaded(ctx context.Context, rdb interface {
+	Do(ctx context.Context, args ...interface{}) *redis.Cmd
+}) error {
 	res, err := rdb.Do(ctx, "COMMAND", "LIST", "FILTERBY", "MODULE", "fs").Slice()
 	if err != nil {
 		return fmt.Errorf("module capability check failed: %w", err)
@@ -895,6 +1031,18 @@ func promptInt(r *bufio.Reader, out io.Writer, label string, def int) (int, erro
 	return i, nil
 }
 
+// parseAddrList splits a comma-separated address list, dropping empty entries.
+func parseAddrList(s string) []string {
+	var out []string
+	for _, a := range strings.Split(s, ",") {
+		a = strings.TrimSpace(a)
+		if a != "" {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
 func splitAddr(addr string) (string, int, error) {
 	parts := strings.Split(addr, ":")
 	if len(parts) != 2 {
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Defaults for the health-check supervisor, used by both `rfs supervise`
+// and `up --supervise`.
+const (
+	defaultSuperviseInterval   = 10 * time.Second
+	defaultSuperviseMaxRestart = 5
+)
+
+// cmdSupervise runs the health-check loop against whatever mount is
+// currently recorded in state.json, restarting it (and reconnecting to
+// Redis) whenever it becomes unhealthy. It is the standalone counterpart to
+// `up --supervise`, for attaching a supervisor to a mount that was already
+// started.
+func cmdSupervise() error {
+	fs := flag.NewFlagSet("supervise", flag.ExitOnError)
+	interval := fs.Duration("interval", defaultSuperviseInterval, "health-check interval")
+	maxRestarts := fs.Int("max-restarts", defaultSuperviseMaxRestart, "give up after this many consecutive restarts (0 = unlimited)")
+	if err := fs.Parse(os.Args[2:]); err != nil {
+		return err
+	}
+
+	st, err := loadState()
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return errors.New("no CLI state found; run 'up' first")
+		}
+		return err
+	}
+	if st.MountPID == 0 || st.Mountpoint == "" {
+		return errors.New("no managed mount found in state; run 'up' first")
+	}
+
+	cfg := configFromState(st)
+	fmt.Printf("Supervising mount at %s (redis: %s)\n", cfg.Mountpoint, cfg.redisSummary())
+	return superviseLoop(cfg, &st, *interval, *maxRestarts)
+}
+
+// configFromState rebuilds enough of a config from saved state to restart
+// the mount daemon. Passwords are deliberately never persisted to
+// state.json, so a password-protected Redis server can't be supervised this
+// way once the original process exits; use `up --supervise` instead, where
+// the password is still in memory from the wizard.
+func configFromState(st state) config {
+	return config{
+		RedisMode:      st.RedisMode,
+		RedisAddr:      st.RedisAddr,
+		RedisDB:        st.RedisDB,
+		SentinelMaster: st.SentinelMaster,
+		SentinelAddrs:  st.SentinelAddrs,
+		ClusterAddrs:   st.ClusterAddrs,
+		RedisKey:       st.RedisKey,
+		Mountpoint:     st.Mountpoint,
+		MountBin:       st.MountBin,
+		RedisServerBin: st.RedisServerBin,
+		RedisLog:       st.RedisLog,
+		MountLog:       st.MountLog,
+		ReadOnly:       st.ReadOnly,
+		AllowOther:     st.AllowOther,
+	}
+}
+
+// superviseLoop runs the health-check loop in the foreground until it is
+// told to stop (SIGINT/SIGTERM) or maxRestarts consecutive restarts have
+// been exhausted. st is rewritten with the new MountPID and saved after
+// every restart, so a concurrent `rfs status` always sees the live pid.
+func superviseLoop(cfg config, st *state, interval time.Duration, maxRestarts int) error {
+	rdb := connectRedis(cfg, 2)
+	defer rdb.Close()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	restarts := 0
+	for {
+		select {
+		case sig := <-sigCh:
+			fmt.Printf("Received signal %v, stopping supervisor (mount left running)\n", sig)
+			return nil
+		case <-ticker.C:
+		}
+
+		if err := probeHealth(context.Background(), rdb, cfg, *st); err != nil {
+			fmt.Printf("Health check failed: %v\n", err)
+			if maxRestarts > 0 && restarts >= maxRestarts {
+				return fmt.Errorf("giving up after %d restarts: %w", restarts, err)
+			}
+			if err := restartMount(cfg, st); err != nil {
+				return fmt.Errorf("restart failed: %w", err)
+			}
+			restarts++
+			fmt.Printf("Recovered mount at %s (restart %d/%s)\n", cfg.Mountpoint, restarts, maxRestartsLabel(maxRestarts))
+			continue
+		}
+		restarts = 0
+	}
+}
+
+// probeHealth runs one health-check cycle: Redis reachability (with its own
+// short retry/backoff), the mount and its daemon process being alive, and a
+// round-trip FS.TOUCH observed through the FUSE mount itself, which catches
+// a hung FUSE layer that a merely-alive process wouldn't.
+func probeHealth(ctx context.Context, rdb redis.UniversalClient, cfg config, st state) error {
+	if err := pingWithBackoff(ctx, rdb, 3, 200*time.Millisecond); err != nil {
+		return fmt.Errorf("redis unreachable: %w", err)
+	}
+
+	if !isMounted(st.Mountpoint) {
+		return fmt.Errorf("%s is not mounted", st.Mountpoint)
+	}
+	if !processAlive(st.MountPID) {
+		return fmt.Errorf("mount daemon (pid %d) is not running", st.MountPID)
+	}
+
+	const checkPath = "/.mount-check"
+	if err := rdb.Do(ctx, "FS.TOUCH", cfg.RedisKey, checkPath).Err(); err != nil {
+		return fmt.Errorf("FS.TOUCH %s: %w", checkPath, err)
+	}
+
+	localPath := filepath.Join(st.Mountpoint, ".mount-check")
+	deadline := time.Now().Add(3 * time.Second)
+	var statErr error
+	for time.Now().Before(deadline) {
+		if _, statErr = os.Stat(localPath); statErr == nil {
+			return nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return fmt.Errorf("FUSE mount did not surface %s: %w", checkPath, statErr)
+}
+
+// pingWithBackoff pings Redis, retrying with exponential backoff on
+// redis.Nil or network errors rather than declaring the backend down on the
+// first transient blip (e.g. mid-failover).
+func pingWithBackoff(ctx context.Context, rdb redis.UniversalClient, attempts int, base time.Duration) error {
+	var err error
+	for i := 0; i < attempts; i++ {
+		pctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		err = rdb.Ping(pctx).Err()
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if i < attempts-1 {
+			time.Sleep(base * time.Duration(uint(1)<<uint(i)))
+		}
+	}
+	return err
+}
+
+// restartMount unmounts and stops the current mount daemon, starts a fresh
+// one, and persists its new pid to state.json, reusing the same unmount
+// fallback chain and pid-management helpers as `down` and `up`.
+func restartMount(cfg config, st *state) error {
+	if isMounted(st.Mountpoint) {
+		if err := unmount(st.Mountpoint); err != nil {
+			fmt.Printf("warning: unmount %s failed: %v\n", st.Mountpoint, err)
+		}
+	}
+	if st.MountPID > 0 {
+		_ = terminatePID(st.MountPID, 2*time.Second)
+	}
+
+	mpid, err := startMountDaemon(cfg)
+	if err != nil {
+		return err
+	}
+	if err := waitForMount(cfg.Mountpoint, 6*time.Second); err != nil {
+		return err
+	}
+
+	st.MountPID = mpid
+	return saveState(*st)
+}
+
+func maxRestartsLabel(maxRestarts int) string {
+	if maxRestarts <= 0 {
+		return "unlimited"
+	}
+	return strconv.Itoa(maxRestarts)
+}
@@ -2,70 +2,272 @@
 package cache
 
 import (
+	"container/list"
 	"sync"
 	"time"
 )
 
-// AttrEntry is a cached attribute entry.
+// DefaultMaxEntries bounds a Cache created via New/NewWithHooks, which don't
+// take an explicit MaxEntries.
+const DefaultMaxEntries = 100_000
+
+// AttrEntry is a cached attribute entry. A Negative entry records that a
+// path was confirmed not to exist, so Data is meaningless when Negative is
+// true.
 type AttrEntry struct {
-	Data   interface{}
-	Expiry time.Time
+	Data     interface{}
+	Expiry   time.Time
+	Negative bool
+}
+
+// Hooks lets a caller observe cache activity (e.g. to increment metrics)
+// without the cache itself knowing anything about where those observations
+// go. Any of these may be nil.
+type Hooks struct {
+	OnHit        func(key string)
+	OnMiss       func(key string)
+	OnSizeChange func(size int)
+	OnEvict      func(key string)
+}
+
+// Options configures a Cache.
+type Options struct {
+	// TTL is how long a positive (Set) entry stays valid.
+	TTL time.Duration
+	// NegativeTTL is how long a SetNegative entry stays valid. Defaults to
+	// TTL if zero.
+	NegativeTTL time.Duration
+	// MaxEntries bounds the cache size; the least-recently-used entry is
+	// evicted once it's exceeded. Defaults to DefaultMaxEntries if <= 0.
+	MaxEntries int
+	Hooks      Hooks
+}
+
+type cacheEntry struct {
+	key   string
+	value AttrEntry
 }
 
-// Cache provides thread-safe TTL-based caching.
+// Cache is a thread-safe, size-bounded LRU with TTL expiry and negative
+// caching. Get, Set, and eviction are O(1) map+list operations, untouched by
+// index size; InvalidatePrefix is handled by a separate path trie (see
+// pathTrie) costing O(len(prefix) + k) for k matching entries, so it never
+// needs an O(n) shift of every other key to stay current.
 type Cache struct {
-	mu  sync.RWMutex
-	m   map[string]AttrEntry
-	ttl time.Duration
+	mu    sync.Mutex
+	ll    *list.List // front = most recently used
+	items map[string]*list.Element
+	index *pathTrie // same keys as items, for InvalidatePrefix
+
+	ttl         time.Duration
+	negativeTTL time.Duration
+	maxEntries  int
+	hooks       Hooks
 }
 
-// New creates a cache with the given TTL.
+// New creates a cache with the given TTL and the default entry cap.
 func New(ttl time.Duration) *Cache {
+	return NewWithOptions(Options{TTL: ttl})
+}
+
+// NewWithHooks creates a cache with the given TTL, observation hooks, and
+// the default entry cap.
+func NewWithHooks(ttl time.Duration, hooks Hooks) *Cache {
+	return NewWithOptions(Options{TTL: ttl, Hooks: hooks})
+}
+
+// NewWithOptions creates a cache with full control over TTL, negative-entry
+// TTL, and the LRU size bound.
+func NewWithOptions(opts Options) *Cache {
+	maxEntries := opts.MaxEntries
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxEntries
+	}
+	negativeTTL := opts.NegativeTTL
+	if negativeTTL == 0 {
+		negativeTTL = opts.TTL
+	}
 	return &Cache{
-		m:   make(map[string]AttrEntry),
-		ttl: ttl,
+		ll:          list.New(),
+		items:       make(map[string]*list.Element),
+		index:       newPathTrie(),
+		ttl:         opts.TTL,
+		negativeTTL: negativeTTL,
+		maxEntries:  maxEntries,
+		hooks:       opts.Hooks,
 	}
 }
 
-// Get returns the cached value and true if found and not expired.
+// Get returns the cached value and true if key has a live, positive entry.
+// A live negative entry (see SetNegative) is reported as a miss here; use
+// GetNegative to observe it.
 func (c *Cache) Get(key string) (interface{}, bool) {
-	c.mu.RLock()
-	entry, ok := c.m[key]
-	c.mu.RUnlock()
-	if !ok || time.Now().After(entry.Expiry) {
+	c.mu.Lock()
+	el, ok := c.lookupLocked(key)
+	if !ok || el.Value.(*cacheEntry).value.Negative {
+		c.mu.Unlock()
+		c.notifyMiss(key)
 		return nil, false
 	}
-	return entry.Data, true
+	data := el.Value.(*cacheEntry).value.Data
+	c.mu.Unlock()
+	c.notifyHit(key)
+	return data, true
+}
+
+// GetNegative reports whether key has a live negative entry, i.e. it was
+// recently confirmed not to exist and callers can short-circuit to ENOENT
+// without a round-trip to the backing store.
+func (c *Cache) GetNegative(key string) bool {
+	c.mu.Lock()
+	el, ok := c.lookupLocked(key)
+	negative := ok && el.Value.(*cacheEntry).value.Negative
+	c.mu.Unlock()
+	if negative {
+		c.notifyHit(key)
+	}
+	return negative
+}
+
+// lookupLocked finds key, evicting it first if expired, and moves a live
+// hit to the front of the LRU list. Caller holds c.mu.
+func (c *Cache) lookupLocked(key string) (*list.Element, bool) {
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(el.Value.(*cacheEntry).value.Expiry) {
+		c.removeElementLocked(el)
+		return nil, false
+	}
+	c.ll.MoveToFront(el)
+	return el, true
 }
 
-// Set stores a value in the cache.
+// Set stores a positive value in the cache.
 func (c *Cache) Set(key string, data interface{}) {
+	c.set(key, AttrEntry{Data: data, Expiry: time.Now().Add(c.ttl)})
+}
+
+// SetNegative records that key does not exist, so a subsequent Lookup or
+// Getattr can return ENOENT immediately for NegativeTTL instead of hitting
+// Redis again.
+func (c *Cache) SetNegative(key string) {
+	c.set(key, AttrEntry{Negative: true, Expiry: time.Now().Add(c.negativeTTL)})
+}
+
+func (c *Cache) set(key string, value AttrEntry) {
 	c.mu.Lock()
-	c.m[key] = AttrEntry{Data: data, Expiry: time.Now().Add(c.ttl)}
+	if el, ok := c.items[key]; ok {
+		el.Value.(*cacheEntry).value = value
+		c.ll.MoveToFront(el)
+		size := len(c.items)
+		c.mu.Unlock()
+		c.notifySize(size)
+		return
+	}
+
+	el := c.ll.PushFront(&cacheEntry{key: key, value: value})
+	c.items[key] = el
+	c.index.insert(key)
+
+	var evicted string
+	didEvict := false
+	if len(c.items) > c.maxEntries {
+		didEvict = c.evictOldestLocked(&evicted)
+	}
+	size := len(c.items)
 	c.mu.Unlock()
+
+	c.notifySize(size)
+	if didEvict {
+		c.notifyEvict(evicted)
+	}
+}
+
+// evictOldestLocked removes the least-recently-used entry. Caller holds c.mu.
+func (c *Cache) evictOldestLocked(outKey *string) bool {
+	back := c.ll.Back()
+	if back == nil {
+		return false
+	}
+	*outKey = back.Value.(*cacheEntry).key
+	c.removeElementLocked(back)
+	return true
+}
+
+// removeElementLocked drops el from the list, map, and path index. Caller
+// holds c.mu.
+func (c *Cache) removeElementLocked(el *list.Element) {
+	key := el.Value.(*cacheEntry).key
+	c.ll.Remove(el)
+	delete(c.items, key)
+	c.index.remove(key)
 }
 
 // Invalidate removes a key from the cache.
 func (c *Cache) Invalidate(key string) {
 	c.mu.Lock()
-	delete(c.m, key)
+	if el, ok := c.items[key]; ok {
+		c.removeElementLocked(el)
+	}
+	size := len(c.items)
 	c.mu.Unlock()
+	c.notifySize(size)
 }
 
-// InvalidatePrefix removes all keys with the given prefix.
+// InvalidatePrefix removes all keys with the given prefix, found via the
+// path trie in time proportional to the prefix and the number of matches,
+// not the size of the cache.
 func (c *Cache) InvalidatePrefix(prefix string) {
 	c.mu.Lock()
-	for k := range c.m {
-		if len(k) >= len(prefix) && k[:len(prefix)] == prefix {
-			delete(c.m, k)
+	for _, key := range c.index.collectPrefix(prefix) {
+		if el, ok := c.items[key]; ok {
+			c.removeElementLocked(el)
 		}
 	}
+	size := len(c.items)
 	c.mu.Unlock()
+	c.notifySize(size)
 }
 
 // InvalidateAll clears the entire cache.
 func (c *Cache) InvalidateAll() {
 	c.mu.Lock()
-	c.m = make(map[string]AttrEntry)
+	c.ll = list.New()
+	c.items = make(map[string]*list.Element)
+	c.index = newPathTrie()
 	c.mu.Unlock()
+	c.notifySize(0)
+}
+
+// Len returns the number of entries currently in the cache, expired or not.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.items)
+}
+
+func (c *Cache) notifySize(size int) {
+	if c.hooks.OnSizeChange != nil {
+		c.hooks.OnSizeChange(size)
+	}
+}
+
+func (c *Cache) notifyHit(key string) {
+	if c.hooks.OnHit != nil {
+		c.hooks.OnHit(key)
+	}
+}
+
+func (c *Cache) notifyMiss(key string) {
+	if c.hooks.OnMiss != nil {
+		c.hooks.OnMiss(key)
+	}
+}
+
+func (c *Cache) notifyEvict(key string) {
+	if c.hooks.OnEvict != nil {
+		c.hooks.OnEvict(key)
+	}
 }
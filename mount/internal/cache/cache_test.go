@@ -0,0 +1,134 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHooksFireOnHitMissAndSizeChange(t *testing.T) {
+	var hits, misses []string
+	var sizes []int
+
+	c := NewWithHooks(time.Minute, Hooks{
+		OnHit:        func(key string) { hits = append(hits, key) },
+		OnMiss:       func(key string) { misses = append(misses, key) },
+		OnSizeChange: func(size int) { sizes = append(sizes, size) },
+	})
+
+	if _, ok := c.Get("/a"); ok {
+		t.Fatal("expected miss on empty cache")
+	}
+	c.Set("/a", 1)
+	if _, ok := c.Get("/a"); !ok {
+		t.Fatal("expected hit after Set")
+	}
+	c.Invalidate("/a")
+
+	if len(misses) != 1 || misses[0] != "/a" {
+		t.Fatalf("misses = %v, want [/a]", misses)
+	}
+	if len(hits) != 1 || hits[0] != "/a" {
+		t.Fatalf("hits = %v, want [/a]", hits)
+	}
+	if len(sizes) != 2 || sizes[0] != 1 || sizes[1] != 0 {
+		t.Fatalf("sizes = %v, want [1 0]", sizes)
+	}
+	if c.Len() != 0 {
+		t.Fatalf("Len() = %d, want 0", c.Len())
+	}
+}
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	var evicted []string
+	c := NewWithOptions(Options{
+		TTL:        time.Minute,
+		MaxEntries: 2,
+		Hooks:      Hooks{OnEvict: func(key string) { evicted = append(evicted, key) }},
+	})
+
+	c.Set("/a", 1)
+	c.Set("/b", 2)
+	c.Get("/a") // touch /a so /b becomes least recently used
+	c.Set("/c", 3)
+
+	if len(evicted) != 1 || evicted[0] != "/b" {
+		t.Fatalf("evicted = %v, want [/b]", evicted)
+	}
+	if _, ok := c.Get("/b"); ok {
+		t.Fatal("expected /b to have been evicted")
+	}
+	if _, ok := c.Get("/a"); !ok {
+		t.Fatal("expected /a to survive eviction")
+	}
+	if c.Len() != 2 {
+		t.Fatalf("Len() = %d, want 2", c.Len())
+	}
+}
+
+func TestNegativeEntries(t *testing.T) {
+	c := NewWithOptions(Options{TTL: time.Minute, NegativeTTL: time.Minute})
+
+	if c.GetNegative("/missing") {
+		t.Fatal("expected no negative entry before SetNegative")
+	}
+	c.SetNegative("/missing")
+	if !c.GetNegative("/missing") {
+		t.Fatal("expected negative entry after SetNegative")
+	}
+	if _, ok := c.Get("/missing"); ok {
+		t.Fatal("Get should not surface a negative entry as a positive hit")
+	}
+
+	// A subsequent Set (the path now exists) clears the negative marking.
+	c.Set("/missing", "now exists")
+	if c.GetNegative("/missing") {
+		t.Fatal("expected negative entry to be replaced by Set")
+	}
+	if v, ok := c.Get("/missing"); !ok || v != "now exists" {
+		t.Fatalf("Get(/missing) = %v, %v, want \"now exists\", true", v, ok)
+	}
+}
+
+func TestInvalidatePrefixMatchesOnlyLiteralPrefix(t *testing.T) {
+	c := New(time.Minute)
+	for _, k := range []string{"/a", "/a/b", "/a/b/c", "/ab", "/x"} {
+		c.Set(k, k)
+	}
+
+	c.InvalidatePrefix("/a/")
+
+	if _, ok := c.Get("/a/b"); ok {
+		t.Fatal("expected /a/b invalidated")
+	}
+	if _, ok := c.Get("/a/b/c"); ok {
+		t.Fatal("expected /a/b/c invalidated")
+	}
+	if _, ok := c.Get("/a"); !ok {
+		t.Fatal("expected /a (no trailing slash) to remain")
+	}
+	if _, ok := c.Get("/ab"); !ok {
+		t.Fatal("expected /ab (not a path descendant) to remain")
+	}
+	if _, ok := c.Get("/x"); !ok {
+		t.Fatal("expected unrelated key to remain")
+	}
+	if c.Len() != 3 {
+		t.Fatalf("Len() = %d, want 3", c.Len())
+	}
+}
+
+// BenchmarkGetWithHooks confirms per-op overhead stays well under a
+// microsecond when hooks (as installed for Prometheus metrics) are present.
+func BenchmarkGetWithHooks(b *testing.B) {
+	c := NewWithHooks(time.Minute, Hooks{
+		OnHit:        func(string) {},
+		OnMiss:       func(string) {},
+		OnSizeChange: func(int) {},
+	})
+	c.Set("/a", 1)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Get("/a")
+	}
+}
@@ -0,0 +1,113 @@
+package cache
+
+// pathTrie indexes a set of cache keys by their raw bytes so
+// InvalidatePrefix can find every key with a given prefix in time
+// proportional to the prefix and the number of matches, not the number of
+// keys in the cache. insert/remove cost is proportional to key length only,
+// independent of how many other keys are indexed -- unlike a sorted slice,
+// which needs an O(n) shift on every insert/remove to stay sorted.
+//
+// Each node's children are a small slice, not a map: a filesystem path's
+// per-node fan-out is small in practice (a handful of distinct next bytes at
+// any point in the tree), so a linear scan is both faster and far lighter on
+// allocations than a map[byte]*pathTrie per node, which would otherwise pay
+// a map header and bucket array at every single byte of every key.
+type pathTrie struct {
+	children []trieEdge
+	terminal bool // true if a key ends exactly at this node
+}
+
+type trieEdge struct {
+	b    byte
+	node *pathTrie
+}
+
+func newPathTrie() *pathTrie {
+	return &pathTrie{}
+}
+
+// child returns t's child for byte b, or nil if there isn't one.
+func (t *pathTrie) child(b byte) *pathTrie {
+	for i := range t.children {
+		if t.children[i].b == b {
+			return t.children[i].node
+		}
+	}
+	return nil
+}
+
+// insert adds key to the trie.
+func (t *pathTrie) insert(key string) {
+	n := t
+	for i := 0; i < len(key); i++ {
+		c := n.child(key[i])
+		if c == nil {
+			c = &pathTrie{}
+			n.children = append(n.children, trieEdge{b: key[i], node: c})
+		}
+		n = c
+	}
+	n.terminal = true
+}
+
+// remove drops key from the trie, pruning any nodes left with neither
+// children nor a terminal key of their own.
+func (t *pathTrie) remove(key string) {
+	path := make([]*pathTrie, 1, len(key)+1)
+	path[0] = t
+	n := t
+	for i := 0; i < len(key); i++ {
+		c := n.child(key[i])
+		if c == nil {
+			return // key not present
+		}
+		path = append(path, c)
+		n = c
+	}
+	if !n.terminal {
+		return
+	}
+	n.terminal = false
+
+	for i := len(path) - 1; i > 0; i-- {
+		child := path[i]
+		if child.terminal || len(child.children) > 0 {
+			break
+		}
+		parent := path[i-1]
+		b := key[i-1]
+		for j := range parent.children {
+			if parent.children[j].b == b {
+				parent.children = append(parent.children[:j], parent.children[j+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// collectPrefix returns every indexed key that starts with prefix.
+func (t *pathTrie) collectPrefix(prefix string) []string {
+	n := t
+	for i := 0; i < len(prefix); i++ {
+		c := n.child(prefix[i])
+		if c == nil {
+			return nil
+		}
+		n = c
+	}
+
+	var out []string
+	n.walk(prefix, &out)
+	return out
+}
+
+// walk appends every key reachable from t to out, where prefix is the key
+// material already consumed to reach t.
+func (t *pathTrie) walk(prefix string, out *[]string) {
+	if t.terminal {
+		*out = append(*out, prefix)
+	}
+	for _, e := range t.children {
+		e.node.walk(prefix+string(e.b), out)
+	}
+}
@@ -0,0 +1,50 @@
+package cache
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestPathTrieCollectPrefix(t *testing.T) {
+	tr := newPathTrie()
+	for _, k := range []string{"/a", "/a/b", "/a/b/c", "/ab", "/x"} {
+		tr.insert(k)
+	}
+
+	got := tr.collectPrefix("/a/")
+	sort.Strings(got)
+	want := []string{"/a/b", "/a/b/c"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("collectPrefix(/a/) = %v, want %v", got, want)
+	}
+
+	if got := tr.collectPrefix("/nope"); got != nil {
+		t.Fatalf("collectPrefix(/nope) = %v, want nil", got)
+	}
+}
+
+func TestPathTrieRemovePrunesDeadNodes(t *testing.T) {
+	tr := newPathTrie()
+	tr.insert("/a/b")
+	tr.remove("/a/b")
+
+	if got := tr.collectPrefix(""); got != nil {
+		t.Fatalf("collectPrefix(\"\") after remove = %v, want nil", got)
+	}
+	if len(tr.children) != 0 {
+		t.Fatalf("expected trie fully pruned back to an empty root, got children: %v", tr.children)
+	}
+}
+
+func TestPathTrieRemoveLeavesSiblingsIntact(t *testing.T) {
+	tr := newPathTrie()
+	tr.insert("/a")
+	tr.insert("/a/b")
+	tr.remove("/a/b")
+
+	got := tr.collectPrefix("")
+	if !reflect.DeepEqual(got, []string{"/a"}) {
+		t.Fatalf("collectPrefix(\"\") after removing /a/b = %v, want [/a]", got)
+	}
+}
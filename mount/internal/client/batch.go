@@ -0,0 +1,134 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Batch queues FS.* commands for a single Redis pipeline round trip, for
+// bulk operations (recursive stat/chmod, tree copy) where issuing one
+// command at a time would be dominated by round-trip latency on a
+// high-latency Redis connection. Queue methods return a Future-style handle
+// whose Result() is only valid after Exec.
+//
+// A Batch is not safe for concurrent use.
+type Batch struct {
+	c    *Client
+	ctx  context.Context
+	pipe redis.Pipeliner
+}
+
+// NewBatch creates a Batch that queues commands against ctx, which is also
+// used for the eventual Exec round trip.
+func (c *Client) NewBatch(ctx context.Context) *Batch {
+	return &Batch{c: c, ctx: ctx, pipe: c.rdb.Pipeline()}
+}
+
+// Len returns the number of commands queued so far.
+func (b *Batch) Len() int {
+	return b.pipe.Len()
+}
+
+// Exec sends every queued command in one round trip. Its own error reports
+// only a problem executing the pipeline itself (e.g. a dropped connection);
+// it is also set to the first per-command FS.* error, but every Future's
+// Result() remains the authoritative way to check that command's outcome.
+func (b *Batch) Exec() error {
+	_, err := b.pipe.Exec(b.ctx)
+	if err != nil && err != redis.Nil {
+		return err
+	}
+	return nil
+}
+
+// StatFuture is the result of a queued Stat, valid after Exec.
+type StatFuture struct{ cmd *redis.Cmd }
+
+// Result parses the queued FS.STAT response, as Client.Stat does.
+func (f *StatFuture) Result() (*StatResult, error) {
+	res, err := f.cmd.Slice()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil // path does not exist
+		}
+		return nil, err
+	}
+	return parseStat(res)
+}
+
+// Stat queues an FS.STAT for path.
+func (b *Batch) Stat(path string) *StatFuture {
+	return &StatFuture{cmd: b.pipe.Do(b.ctx, "FS.STAT", b.c.key, path)}
+}
+
+// CatFuture is the result of a queued Cat, valid after Exec.
+type CatFuture struct{ cmd *redis.Cmd }
+
+// Result parses the queued FS.CAT response, as Client.Cat does.
+func (f *CatFuture) Result() ([]byte, error) {
+	val, err := f.cmd.Result()
+	if err != nil {
+		return nil, err
+	}
+	switch v := val.(type) {
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return v, nil
+	default:
+		return nil, fmt.Errorf("unexpected CAT response type: %T", val)
+	}
+}
+
+// Cat queues an FS.CAT for path.
+func (b *Batch) Cat(path string) *CatFuture {
+	return &CatFuture{cmd: b.pipe.Do(b.ctx, "FS.CAT", b.c.key, path)}
+}
+
+// ErrFuture is the result of a queued command that only reports success or
+// failure, valid after Exec.
+type ErrFuture struct{ cmd *redis.Cmd }
+
+// Result returns the queued command's error, if any.
+func (f *ErrFuture) Result() error {
+	return f.cmd.Err()
+}
+
+// Echo queues an FS.ECHO for path (creates or overwrites).
+func (b *Batch) Echo(path string, data []byte) *ErrFuture {
+	return &ErrFuture{cmd: b.pipe.Do(b.ctx, "FS.ECHO", b.c.key, path, data)}
+}
+
+// WriteRange queues an FS.WRITE for path, as Client.WriteRange does.
+func (b *Batch) WriteRange(path string, offset int64, data []byte) *ErrFuture {
+	return &ErrFuture{cmd: b.pipe.Do(b.ctx, "FS.WRITE", b.c.key, path, offset, data)}
+}
+
+// Touch queues an FS.TOUCH for path.
+func (b *Batch) Touch(path string) *ErrFuture {
+	return &ErrFuture{cmd: b.pipe.Do(b.ctx, "FS.TOUCH", b.c.key, path)}
+}
+
+// Mkdir queues an FS.MKDIR for path (with PARENTS).
+func (b *Batch) Mkdir(path string) *ErrFuture {
+	return &ErrFuture{cmd: b.pipe.Do(b.ctx, "FS.MKDIR", b.c.key, path, "PARENTS")}
+}
+
+// Chmod queues an FS.CHMOD for path.
+func (b *Batch) Chmod(path string, mode uint32) *ErrFuture {
+	modeStr := fmt.Sprintf("%04o", mode)
+	return &ErrFuture{cmd: b.pipe.Do(b.ctx, "FS.CHMOD", b.c.key, path, modeStr)}
+}
+
+// Chown queues an FS.CHOWN for path.
+func (b *Batch) Chown(path string, uid, gid uint32) *ErrFuture {
+	return &ErrFuture{cmd: b.pipe.Do(b.ctx, "FS.CHOWN", b.c.key, path, uid, gid)}
+}
+
+// Utimens queues an FS.UTIMENS for path.
+func (b *Batch) Utimens(path string, atimeMs, mtimeMs int64) *ErrFuture {
+	return &ErrFuture{cmd: b.pipe.Do(b.ctx, "FS.UTIMENS", b.c.key, path, atimeMs, mtimeMs)}
+}
@@ -5,24 +5,131 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
-// Client wraps a go-redis client with FS.* command methods.
+// CommandObserver is notified after each FS.* command completes, with the
+// command name and its round-trip latency.
+type CommandObserver func(cmd string, dur time.Duration)
+
+// Client wraps a go-redis client with FS.* command methods. rdb is
+// redis.UniversalClient rather than *redis.Client so a single mount can be
+// backed by a standalone server, a Sentinel-managed failover client, or a
+// Cluster client interchangeably.
 type Client struct {
-	rdb *redis.Client
+	rdb redis.UniversalClient
 	key string // Redis key holding the filesystem
+
+	onCommand CommandObserver
+
+	txnMu sync.Mutex
+	txnID string // non-empty while a BeginTxn is in progress
 }
 
 // New creates a new FS client for the given Redis key.
-func New(rdb *redis.Client, key string) *Client {
+func New(rdb redis.UniversalClient, key string) *Client {
 	return &Client{rdb: rdb, key: key}
 }
 
+// SetCommandObserver installs obs to be called after every FS.* command,
+// e.g. to feed a Prometheus histogram. Pass nil to disable.
+func (c *Client) SetCommandObserver(obs CommandObserver) {
+	c.onCommand = obs
+}
+
+// do runs an FS.* command through go-redis, timing it for onCommand if set.
+// args[0] is the command name (e.g. "FS.STAT").
+func (c *Client) do(ctx context.Context, args ...interface{}) *redis.Cmd {
+	start := time.Now()
+	cmd := c.rdb.Do(ctx, args...)
+	if c.onCommand != nil {
+		name, _ := args[0].(string)
+		c.onCommand(name, time.Since(start))
+	}
+	return cmd
+}
+
+// BeginTxn starts a whole-FS transaction: every mutating FS.* command this
+// Client issues until CommitTxn/AbortTxn is tagged with the returned id so
+// the server stages it in a CoW view instead of applying it to the live
+// tree. Only one transaction may be active per Client at a time.
+func (c *Client) BeginTxn(ctx context.Context) (string, error) {
+	c.txnMu.Lock()
+	defer c.txnMu.Unlock()
+	if c.txnID != "" {
+		return "", fmt.Errorf("client: transaction %q already active", c.txnID)
+	}
+	id, err := c.do(ctx, "FS.BEGIN", c.key).Text()
+	if err != nil {
+		return "", err
+	}
+	c.txnID = id
+	return id, nil
+}
+
+// CommitTxn atomically swaps every write staged by the active transaction
+// into the live tree, then clears it.
+func (c *Client) CommitTxn(ctx context.Context) error {
+	c.txnMu.Lock()
+	id := c.txnID
+	c.txnMu.Unlock()
+	if id == "" {
+		return errors.New("client: no active transaction")
+	}
+	if err := c.do(ctx, "FS.COMMIT", c.key, id).Err(); err != nil {
+		return err
+	}
+	c.txnMu.Lock()
+	c.txnID = ""
+	c.txnMu.Unlock()
+	return nil
+}
+
+// AbortTxn discards every write staged by the active transaction, leaving
+// the live tree untouched.
+func (c *Client) AbortTxn(ctx context.Context) error {
+	c.txnMu.Lock()
+	id := c.txnID
+	c.txnMu.Unlock()
+	if id == "" {
+		return errors.New("client: no active transaction")
+	}
+	if err := c.do(ctx, "FS.ABORT", c.key, id).Err(); err != nil {
+		return err
+	}
+	c.txnMu.Lock()
+	c.txnID = ""
+	c.txnMu.Unlock()
+	return nil
+}
+
+// ActiveTxn returns the id of the in-progress transaction, or "" if none.
+func (c *Client) ActiveTxn() string {
+	c.txnMu.Lock()
+	defer c.txnMu.Unlock()
+	return c.txnID
+}
+
+// doTxn is like do, but tags the command with the active transaction (if
+// any) so it gets staged instead of applied to the live tree. Used by every
+// method that mutates the FS; read-only commands use do directly since
+// reads always see the live tree, not a transaction's staged writes.
+func (c *Client) doTxn(ctx context.Context, args ...interface{}) *redis.Cmd {
+	c.txnMu.Lock()
+	id := c.txnID
+	c.txnMu.Unlock()
+	if id != "" {
+		args = append(args, "TXN", id)
+	}
+	return c.do(ctx, args...)
+}
+
 // Stat returns metadata for a path. Returns nil, nil if path does not exist.
 func (c *Client) Stat(ctx context.Context, path string) (*StatResult, error) {
-	res, err := c.rdb.Do(ctx, "FS.STAT", c.key, path).Slice()
+	res, err := c.do(ctx, "FS.STAT", c.key, path).Slice()
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
 			return nil, nil // path does not exist
@@ -34,7 +141,7 @@ func (c *Client) Stat(ctx context.Context, path string) (*StatResult, error) {
 
 // Cat returns the file content at path.
 func (c *Client) Cat(ctx context.Context, path string) ([]byte, error) {
-	val, err := c.rdb.Do(ctx, "FS.CAT", c.key, path).Result()
+	val, err := c.do(ctx, "FS.CAT", c.key, path).Result()
 	if err != nil {
 		return nil, err
 	}
@@ -48,86 +155,205 @@ func (c *Client) Cat(ctx context.Context, path string) ([]byte, error) {
 	}
 }
 
+// Read returns up to length bytes of path starting at offset, via FS.READ.
+func (c *Client) Read(ctx context.Context, path string, offset, length int64) ([]byte, error) {
+	val, err := c.do(ctx, "FS.READ", c.key, path, offset, length).Result()
+	if err != nil {
+		return nil, err
+	}
+	switch v := val.(type) {
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return v, nil
+	default:
+		return nil, fmt.Errorf("unexpected READ response type: %T", val)
+	}
+}
+
+// WriteRange overwrites length(data) bytes of path starting at offset via
+// FS.WRITE, extending the file with zero bytes first if offset is past the
+// current end. Used for chunked writes; callers needing to replace the
+// whole file should use Echo instead.
+func (c *Client) WriteRange(ctx context.Context, path string, offset int64, data []byte) error {
+	return c.doTxn(ctx, "FS.WRITE", c.key, path, offset, data).Err()
+}
+
+// Size returns the current size in bytes of path, or -1 if path doesn't
+// exist.
+func (c *Client) Size(ctx context.Context, path string) (int64, error) {
+	st, err := c.Stat(ctx, path)
+	if err != nil {
+		return -1, err
+	}
+	if st == nil {
+		return -1, nil
+	}
+	return st.Size, nil
+}
+
 // Echo writes content to a file (creates or overwrites).
 func (c *Client) Echo(ctx context.Context, path string, data []byte) error {
-	return c.rdb.Do(ctx, "FS.ECHO", c.key, path, data).Err()
+	return c.doTxn(ctx, "FS.ECHO", c.key, path, data).Err()
 }
 
 // EchoAppend appends content to a file.
 func (c *Client) EchoAppend(ctx context.Context, path string, data []byte) error {
-	return c.rdb.Do(ctx, "FS.ECHO", c.key, path, data, "APPEND").Err()
+	return c.doTxn(ctx, "FS.ECHO", c.key, path, data, "APPEND").Err()
 }
 
 // Touch creates an empty file.
 func (c *Client) Touch(ctx context.Context, path string) error {
-	return c.rdb.Do(ctx, "FS.TOUCH", c.key, path).Err()
+	return c.doTxn(ctx, "FS.TOUCH", c.key, path).Err()
 }
 
 // Mkdir creates a directory (with PARENTS to auto-create ancestors).
 func (c *Client) Mkdir(ctx context.Context, path string) error {
-	return c.rdb.Do(ctx, "FS.MKDIR", c.key, path, "PARENTS").Err()
+	return c.doTxn(ctx, "FS.MKDIR", c.key, path, "PARENTS").Err()
 }
 
 // Rm removes a file, directory, or symlink.
 func (c *Client) Rm(ctx context.Context, path string) error {
-	return c.rdb.Do(ctx, "FS.RM", c.key, path).Err()
+	return c.doTxn(ctx, "FS.RM", c.key, path).Err()
 }
 
 // Ls returns the children of a directory.
 func (c *Client) Ls(ctx context.Context, path string) ([]string, error) {
-	return c.rdb.Do(ctx, "FS.LS", c.key, path).StringSlice()
+	return c.do(ctx, "FS.LS", c.key, path).StringSlice()
 }
 
 // LsLong returns detailed directory listing.
 func (c *Client) LsLong(ctx context.Context, path string) ([]LsEntry, error) {
-	res, err := c.rdb.Do(ctx, "FS.LS", c.key, path, "LONG").Slice()
+	res, err := c.do(ctx, "FS.LS", c.key, path, "LONG").Slice()
 	if err != nil {
 		return nil, err
 	}
 	return parseLsLong(res)
 }
 
-// Mv renames/moves a path.
+// Mv renames/moves a path, replacing dst if it already exists.
 func (c *Client) Mv(ctx context.Context, src, dst string) error {
-	return c.rdb.Do(ctx, "FS.MV", c.key, src, dst).Err()
+	return c.doTxn(ctx, "FS.MV", c.key, src, dst).Err()
+}
+
+// MvNoReplace renames/moves src to dst, failing rather than overwriting if
+// dst already exists.
+func (c *Client) MvNoReplace(ctx context.Context, src, dst string) error {
+	return c.doTxn(ctx, "FS.MV", c.key, src, dst, "NOREPLACE").Err()
+}
+
+// MvExchange atomically swaps a and b in place, as if each were renamed to
+// the other's path in a single transaction.
+func (c *Client) MvExchange(ctx context.Context, a, b string) error {
+	return c.doTxn(ctx, "FS.MVEXCHANGE", c.key, a, b).Err()
 }
 
 // Ln creates a symbolic link.
 func (c *Client) Ln(ctx context.Context, target, linkpath string) error {
-	return c.rdb.Do(ctx, "FS.LN", c.key, target, linkpath).Err()
+	return c.doTxn(ctx, "FS.LN", c.key, target, linkpath).Err()
 }
 
 // Readlink returns the target of a symbolic link.
 func (c *Client) Readlink(ctx context.Context, path string) (string, error) {
-	return c.rdb.Do(ctx, "FS.READLINK", c.key, path).Text()
+	return c.do(ctx, "FS.READLINK", c.key, path).Text()
 }
 
 // Chmod changes file permissions.
 func (c *Client) Chmod(ctx context.Context, path string, mode uint32) error {
 	modeStr := fmt.Sprintf("%04o", mode)
-	return c.rdb.Do(ctx, "FS.CHMOD", c.key, path, modeStr).Err()
+	return c.doTxn(ctx, "FS.CHMOD", c.key, path, modeStr).Err()
 }
 
 // Chown changes file owner and group.
 func (c *Client) Chown(ctx context.Context, path string, uid, gid uint32) error {
-	return c.rdb.Do(ctx, "FS.CHOWN", c.key, path, uid, gid).Err()
+	return c.doTxn(ctx, "FS.CHOWN", c.key, path, uid, gid).Err()
 }
 
 // Truncate truncates or extends a file to the given length.
 func (c *Client) Truncate(ctx context.Context, path string, size int64) error {
-	return c.rdb.Do(ctx, "FS.TRUNCATE", c.key, path, size).Err()
+	return c.doTxn(ctx, "FS.TRUNCATE", c.key, path, size).Err()
 }
 
 // Utimens sets access and modification times (milliseconds). -1 means don't change.
 func (c *Client) Utimens(ctx context.Context, path string, atimeMs, mtimeMs int64) error {
-	return c.rdb.Do(ctx, "FS.UTIMENS", c.key, path, atimeMs, mtimeMs).Err()
+	return c.doTxn(ctx, "FS.UTIMENS", c.key, path, atimeMs, mtimeMs).Err()
+}
+
+// GetXattr returns the value of an extended attribute, or nil if it isn't set.
+func (c *Client) GetXattr(ctx context.Context, path, name string) ([]byte, error) {
+	val, err := c.do(ctx, "FS.GETXATTR", c.key, path, name).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil // attribute not set
+		}
+		return nil, err
+	}
+	switch v := val.(type) {
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return v, nil
+	default:
+		return nil, fmt.Errorf("unexpected GETXATTR response type: %T", val)
+	}
+}
+
+// SetXattr sets an extended attribute on path.
+func (c *Client) SetXattr(ctx context.Context, path, name string, value []byte) error {
+	return c.doTxn(ctx, "FS.SETXATTR", c.key, path, name, value).Err()
+}
+
+// ListXattr returns the names of the extended attributes set on path.
+func (c *Client) ListXattr(ctx context.Context, path string) ([]string, error) {
+	return c.do(ctx, "FS.LISTXATTR", c.key, path).StringSlice()
+}
+
+// RemoveXattr removes an extended attribute from path.
+func (c *Client) RemoveXattr(ctx context.Context, path, name string) error {
+	return c.doTxn(ctx, "FS.REMOVEXATTR", c.key, path, name).Err()
 }
 
 // Info returns filesystem-level statistics.
 func (c *Client) Info(ctx context.Context) (*InfoResult, error) {
-	res, err := c.rdb.Do(ctx, "FS.INFO", c.key).Slice()
+	res, err := c.do(ctx, "FS.INFO", c.key).Slice()
 	if err != nil {
 		return nil, err
 	}
 	return parseInfo(res)
 }
+
+// cryptoConfigKey is a reserved plain Redis string key (not an FS.* path)
+// used to persist an encrypted mount's key-derivation parameters. It's
+// namespaced under the FS key so multiple filesystems in the same Redis
+// instance don't collide.
+func (c *Client) cryptoConfigKey() string {
+	return c.key + ":cryptoconf"
+}
+
+// ReadCryptoConfig returns the raw bytes previously stored by
+// WriteCryptoConfig, or nil if none have been stored yet. The content is
+// opaque to Client; it's the crypto package's serialized Config.
+func (c *Client) ReadCryptoConfig(ctx context.Context) ([]byte, error) {
+	data, err := c.rdb.Get(ctx, c.cryptoConfigKey()).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+// WriteCryptoConfig persists data (the crypto package's serialized Config)
+// under the mount's reserved cryptoconf key.
+func (c *Client) WriteCryptoConfig(ctx context.Context, data []byte) error {
+	return c.rdb.Set(ctx, c.cryptoConfigKey(), data, 0).Err()
+}
+
+// WriteCryptoConfigIfAbsent persists data under the mount's reserved
+// cryptoconf key only if nothing is stored there yet, so two mounts racing
+// to initialize encryption for the first time can't clobber each other's
+// Config. Reports whether this call's data won.
+func (c *Client) WriteCryptoConfigIfAbsent(ctx context.Context, data []byte) (bool, error) {
+	return c.rdb.SetNX(ctx, c.cryptoConfigKey(), data, 0).Result()
+}
@@ -0,0 +1,46 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// TestDoPropagatesContextCancellation verifies that a canceled ctx reaches
+// go-redis rather than being swallowed or replaced in between, so a FUSE
+// request interrupted by the kernel aborts its Redis command promptly.
+func TestDoPropagatesContextCancellation(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1"})
+	c := New(rdb, "fs")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := c.Stat(ctx, "/x"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Stat with canceled ctx = %v, want context.Canceled", err)
+	}
+	if _, err := c.Cat(ctx, "/x"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Cat with canceled ctx = %v, want context.Canceled", err)
+	}
+	if _, err := c.LsLong(ctx, "/x"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("LsLong with canceled ctx = %v, want context.Canceled", err)
+	}
+}
+
+// TestTxnRequiresActiveTransaction verifies Commit/AbortTxn reject a
+// Client with no in-progress transaction before ever issuing a command.
+func TestTxnRequiresActiveTransaction(t *testing.T) {
+	c := New(redis.NewClient(&redis.Options{Addr: "127.0.0.1:1"}), "fs")
+
+	if got := c.ActiveTxn(); got != "" {
+		t.Fatalf("ActiveTxn() = %q, want \"\"", got)
+	}
+	if err := c.CommitTxn(context.Background()); err == nil {
+		t.Fatal("CommitTxn with no active transaction should return an error")
+	}
+	if err := c.AbortTxn(context.Background()); err == nil {
+		t.Fatal("AbortTxn with no active transaction should return an error")
+	}
+}
@@ -0,0 +1,92 @@
+package client
+
+import (
+	"os"
+	"time"
+)
+
+// FileInfo adapts a StatResult to the standard library's os.FileInfo, so
+// callers using io/fs-style traversal can reach the raw type/mode/uid/gid
+// and millisecond timestamps Redis FS returns via Sys(), which os.FileInfo
+// otherwise hides.
+type FileInfo struct {
+	name string
+	st   *StatResult
+}
+
+// NewFileInfo adapts st, using name (the base name of the path it was
+// fetched for — StatResult itself carries no name) as the FileInfo's Name().
+func NewFileInfo(name string, st *StatResult) *FileInfo {
+	return &FileInfo{name: name, st: st}
+}
+
+// Name implements os.FileInfo.
+func (fi *FileInfo) Name() string { return fi.name }
+
+// Size implements os.FileInfo.
+func (fi *FileInfo) Size() int64 { return fi.st.Size }
+
+// Mode implements os.FileInfo.
+func (fi *FileInfo) Mode() os.FileMode { return ToFileMode(fi.st) }
+
+// ModTime implements os.FileInfo.
+func (fi *FileInfo) ModTime() time.Time { return time.UnixMilli(fi.st.Mtime) }
+
+// IsDir implements os.FileInfo.
+func (fi *FileInfo) IsDir() bool { return fi.st.Type == "dir" }
+
+// Sys implements os.FileInfo, returning the underlying *StatResult so
+// callers can reach ctime/atime and the numeric uid/gid.
+func (fi *FileInfo) Sys() interface{} { return fi.st }
+
+var _ os.FileInfo = (*FileInfo)(nil)
+
+// ToFileMode maps a StatResult's Type and octal Mode to an os.FileMode,
+// ORing in the POSIX permission bits including setuid/setgid/sticky.
+func ToFileMode(sr *StatResult) os.FileMode {
+	var mode os.FileMode
+	switch sr.Type {
+	case "dir":
+		mode |= os.ModeDir
+	case "symlink":
+		mode |= os.ModeSymlink
+	}
+
+	mode |= os.FileMode(sr.Mode & 0777)
+	if sr.Mode&04000 != 0 {
+		mode |= os.ModeSetuid
+	}
+	if sr.Mode&02000 != 0 {
+		mode |= os.ModeSetgid
+	}
+	if sr.Mode&01000 != 0 {
+		mode |= os.ModeSticky
+	}
+	return mode
+}
+
+// FromFileMode is the inverse of ToFileMode: it splits an os.FileMode into
+// the Redis FS type string and the octal permission bits (including
+// setuid/setgid/sticky) StatResult.Mode carries, so writers can round-trip.
+func FromFileMode(mode os.FileMode) (typ string, perm uint32) {
+	switch {
+	case mode&os.ModeDir != 0:
+		typ = "dir"
+	case mode&os.ModeSymlink != 0:
+		typ = "symlink"
+	default:
+		typ = "file"
+	}
+
+	perm = uint32(mode.Perm())
+	if mode&os.ModeSetuid != 0 {
+		perm |= 04000
+	}
+	if mode&os.ModeSetgid != 0 {
+		perm |= 02000
+	}
+	if mode&os.ModeSticky != 0 {
+		perm |= 01000
+	}
+	return typ, perm
+}
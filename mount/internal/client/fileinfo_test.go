@@ -0,0 +1,71 @@
+package client
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestToFileMode(t *testing.T) {
+	cases := []struct {
+		name string
+		sr   *StatResult
+		want os.FileMode
+	}{
+		{"file", &StatResult{Type: "file", Mode: 0644}, 0644},
+		{"dir", &StatResult{Type: "dir", Mode: 0755}, os.ModeDir | 0755},
+		{"symlink", &StatResult{Type: "symlink", Mode: 0777}, os.ModeSymlink | 0777},
+		{"setuid", &StatResult{Type: "file", Mode: 04755}, os.ModeSetuid | 0755},
+		{"setgid", &StatResult{Type: "dir", Mode: 02755}, os.ModeDir | os.ModeSetgid | 0755},
+		{"sticky", &StatResult{Type: "dir", Mode: 01777}, os.ModeDir | os.ModeSticky | 0777},
+	}
+
+	for _, tc := range cases {
+		if got := ToFileMode(tc.sr); got != tc.want {
+			t.Errorf("%s: ToFileMode() = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestFromFileModeRoundTrip(t *testing.T) {
+	cases := []os.FileMode{
+		0644,
+		os.ModeDir | 0755,
+		os.ModeSymlink | 0777,
+		os.ModeSetuid | 0755,
+		os.ModeDir | os.ModeSetgid | 0755,
+		os.ModeDir | os.ModeSticky | 0777,
+	}
+
+	for _, mode := range cases {
+		typ, perm := FromFileMode(mode)
+		back := ToFileMode(&StatResult{Type: typ, Mode: perm})
+		if back != mode {
+			t.Errorf("round trip %v: got %v via (type=%q, perm=%04o)", mode, back, typ, perm)
+		}
+	}
+}
+
+func TestFileInfo(t *testing.T) {
+	st := &StatResult{Type: "dir", Mode: 0755, Size: 4096, Mtime: 1_700_000_000_000}
+	fi := NewFileInfo("sub", st)
+
+	if fi.Name() != "sub" {
+		t.Errorf("Name() = %q, want %q", fi.Name(), "sub")
+	}
+	if fi.Size() != 4096 {
+		t.Errorf("Size() = %d, want 4096", fi.Size())
+	}
+	if !fi.IsDir() {
+		t.Error("IsDir() = false, want true")
+	}
+	if fi.Mode() != os.ModeDir|0755 {
+		t.Errorf("Mode() = %v, want %v", fi.Mode(), os.ModeDir|0755)
+	}
+	if want := time.UnixMilli(1_700_000_000_000); !fi.ModTime().Equal(want) {
+		t.Errorf("ModTime() = %v, want %v", fi.ModTime(), want)
+	}
+	if sys, ok := fi.Sys().(*StatResult); !ok || sys != st {
+		t.Errorf("Sys() = %v, want underlying *StatResult", fi.Sys())
+	}
+}
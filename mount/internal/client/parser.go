@@ -3,6 +3,7 @@ package client
 import (
 	"fmt"
 	"strconv"
+	"strings"
 )
 
 // StatResult holds parsed FS.STAT response.
@@ -15,6 +16,11 @@ type StatResult struct {
 	Ctime int64 // milliseconds since epoch
 	Mtime int64
 	Atime int64
+
+	// Xattrs holds the path's extended attributes, populated only when the
+	// server includes an "xattrs" field in the FS.STAT response. Nil if the
+	// server didn't report it (callers should fall back to FS.LISTXATTR).
+	Xattrs map[string][]byte
 }
 
 // LsEntry holds one entry from FS.LS LONG.
@@ -33,6 +39,12 @@ type InfoResult struct {
 	Symlinks       int64
 	TotalDataBytes int64
 	TotalInodes    int64
+
+	// RangeSupport reports whether the server accepts FS.WRITE (ranged,
+	// partial-file writes) in addition to the FS.READ it has always
+	// supported. False on servers that predate FS.WRITE or otherwise omit
+	// the "range_support" field from FS.INFO.
+	RangeSupport bool
 }
 
 // parseStat parses the flat [field, value, ...] array from FS.STAT.
@@ -59,9 +71,42 @@ func parseStat(res []interface{}) (*StatResult, error) {
 	sr.Ctime = toInt64(m["ctime"])
 	sr.Mtime = toInt64(m["mtime"])
 	sr.Atime = toInt64(m["atime"])
+	if raw, ok := m["xattrs"]; ok {
+		sr.Xattrs = parseXattrs(raw)
+	}
 	return sr, nil
 }
 
+// parseXattrs parses the "xattrs" field of an FS.STAT response: a flat
+// [name, value, name, value, ...] array.
+func parseXattrs(raw interface{}) map[string][]byte {
+	arr, ok := raw.([]interface{})
+	if !ok || len(arr) == 0 {
+		return nil
+	}
+
+	xattrs := make(map[string][]byte, len(arr)/2)
+	for i := 0; i+1 < len(arr); i += 2 {
+		name, ok := arr[i].(string)
+		if !ok {
+			continue
+		}
+		xattrs[name] = toBytes(arr[i+1])
+	}
+	return xattrs
+}
+
+func toBytes(v interface{}) []byte {
+	switch val := v.(type) {
+	case string:
+		return []byte(val)
+	case []byte:
+		return val
+	default:
+		return nil
+	}
+}
+
 // parseLsLong parses the array of [name, type, mode, size, mtime] arrays.
 func parseLsLong(res []interface{}) ([]LsEntry, error) {
 	entries := make([]LsEntry, 0, len(res))
@@ -103,9 +148,26 @@ func parseInfo(res []interface{}) (*InfoResult, error) {
 		Symlinks:       toInt64(m["symlinks"]),
 		TotalDataBytes: toInt64(m["total_data_bytes"]),
 		TotalInodes:    toInt64(m["total_inodes"]),
+		RangeSupport:   toBool(m["range_support"]),
 	}, nil
 }
 
+func toBool(v interface{}) bool {
+	switch val := v.(type) {
+	case int64:
+		return val != 0
+	case int:
+		return val != 0
+	case string:
+		return val == "1" || strings.EqualFold(val, "true")
+	case []byte:
+		s := string(val)
+		return s == "1" || strings.EqualFold(s, "true")
+	default:
+		return false
+	}
+}
+
 func toString(v interface{}) string {
 	if v == nil {
 		return ""
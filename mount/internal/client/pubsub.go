@@ -0,0 +1,91 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultInvalidationChannel is the pub/sub channel FS.* writers publish
+// invalidated paths to, used to keep multiple mounts of the same Redis FS
+// key cache-coherent.
+const DefaultInvalidationChannel = "fsinvalidate"
+
+// InvalidationChannel abstracts a pub/sub message stream so the consume loop
+// can be unit tested without a live Redis connection. *redis.PubSub
+// satisfies this interface.
+type InvalidationChannel interface {
+	Channel(opts ...redis.ChannelOption) <-chan *redis.Message
+	Close() error
+}
+
+// Subscribe opens a pub/sub subscription on channel.
+func (c *Client) Subscribe(ctx context.Context, channel string) InvalidationChannel {
+	return c.rdb.Subscribe(ctx, channel)
+}
+
+// SubscribeInvalidations subscribes to channel and, for each message
+// received, calls onInvalidate with the message payload (the invalidated
+// path). If the subscription drops, onDisconnect is called once so the
+// caller can flush its caches to avoid split-brain, then the subscription is
+// retried with backoff until ctx is canceled.
+func (c *Client) SubscribeInvalidations(ctx context.Context, channel string, onInvalidate func(path string), onDisconnect func()) {
+	const (
+		minBackoff = time.Second
+		maxBackoff = 30 * time.Second
+	)
+
+	go func() {
+		backoff := minBackoff
+		for ctx.Err() == nil {
+			connectedAt := time.Now()
+			sub := c.Subscribe(ctx, channel)
+			ConsumeInvalidations(ctx, sub, onInvalidate, onDisconnect)
+			sub.Close()
+
+			if ctx.Err() != nil {
+				return
+			}
+
+			// A subscription that stayed up a while was healthy; don't let
+			// an old backoff linger and slow down the next reconnect.
+			if time.Since(connectedAt) > maxBackoff {
+				backoff = minBackoff
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			if backoff *= 2; backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		}
+	}()
+}
+
+// ConsumeInvalidations reads messages from ch until it closes or ctx is
+// done, calling onInvalidate for each message. It returns once the
+// subscription is no longer usable, calling onDisconnect first if the
+// channel was closed out from under it (as opposed to a clean ctx cancel).
+func ConsumeInvalidations(ctx context.Context, ch InvalidationChannel, onInvalidate func(path string), onDisconnect func()) {
+	msgs := ch.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-msgs:
+			if !ok {
+				if onDisconnect != nil {
+					onDisconnect()
+				}
+				return
+			}
+			if onInvalidate != nil {
+				onInvalidate(msg.Payload)
+			}
+		}
+	}
+}
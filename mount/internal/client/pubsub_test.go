@@ -0,0 +1,89 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// fakeInvalidationChannel is a fake InvalidationChannel for testing
+// ConsumeInvalidations without a live Redis subscription.
+type fakeInvalidationChannel struct {
+	ch     chan *redis.Message
+	closed bool
+}
+
+func (f *fakeInvalidationChannel) Channel(opts ...redis.ChannelOption) <-chan *redis.Message {
+	return f.ch
+}
+
+func (f *fakeInvalidationChannel) Close() error {
+	f.closed = true
+	return nil
+}
+
+func TestConsumeInvalidations(t *testing.T) {
+	fake := &fakeInvalidationChannel{ch: make(chan *redis.Message, 2)}
+
+	var got []string
+	done := make(chan struct{})
+	go func() {
+		ConsumeInvalidations(context.Background(), fake, func(path string) {
+			got = append(got, path)
+		}, nil)
+		close(done)
+	}()
+
+	fake.ch <- &redis.Message{Payload: "/a/b"}
+	fake.ch <- &redis.Message{Payload: "/x"}
+	close(fake.ch)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("ConsumeInvalidations did not return after channel closed")
+	}
+
+	if len(got) != 2 || got[0] != "/a/b" || got[1] != "/x" {
+		t.Fatalf("unexpected invalidated paths: %v", got)
+	}
+}
+
+func TestConsumeInvalidationsCallsOnDisconnect(t *testing.T) {
+	fake := &fakeInvalidationChannel{ch: make(chan *redis.Message)}
+	close(fake.ch)
+
+	disconnected := false
+	ConsumeInvalidations(context.Background(), fake, nil, func() {
+		disconnected = true
+	})
+
+	if !disconnected {
+		t.Fatal("expected onDisconnect to be called when the channel closes")
+	}
+}
+
+func TestConsumeInvalidationsStopsOnContextCancel(t *testing.T) {
+	fake := &fakeInvalidationChannel{ch: make(chan *redis.Message)}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	disconnected := false
+	done := make(chan struct{})
+	go func() {
+		ConsumeInvalidations(ctx, fake, nil, func() { disconnected = true })
+		close(done)
+	}()
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("ConsumeInvalidations did not return after context cancel")
+	}
+	if disconnected {
+		t.Fatal("onDisconnect should not fire on a clean context cancel")
+	}
+}
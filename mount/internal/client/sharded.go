@@ -0,0 +1,370 @@
+package client
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// vnodesPerBackend is the number of virtual nodes each backend contributes
+// to the hash ring (a Ketama-style ring typically uses 128-256).
+const vnodesPerBackend = 160
+
+// ShardedClient implements the same FS.* surface as Client, but routes each
+// command to one of several Redis backends chosen by consistent hashing on
+// the target path's directory. A directory and its children always hash to
+// the same backend, so FS.LS and the entries it returns stay consistent.
+type ShardedClient struct {
+	key string // Redis key holding the filesystem, shared across all backends
+
+	// HashKey derives the ring lookup key from a path. Defaults to the
+	// path's parent directory. Override to change colocation, e.g. hash
+	// only the top two path components to keep a whole subtree together.
+	HashKey func(path string) string
+
+	mu       sync.RWMutex
+	ring     []uint32           // sorted vnode hashes
+	vnodes   map[uint32]string  // vnode hash -> backend addr
+	backends map[string]*Client // backend addr -> per-shard client
+
+	onCommand CommandObserver
+}
+
+// NewSharded creates an empty sharded client for the given Redis key. Add
+// backends with AddNode before issuing commands.
+func NewSharded(key string) *ShardedClient {
+	return &ShardedClient{
+		key:      key,
+		HashKey:  defaultHashKey,
+		vnodes:   make(map[uint32]string),
+		backends: make(map[string]*Client),
+	}
+}
+
+// defaultHashKey hashes a path's parent directory, so a directory and its
+// children land on the same shard.
+func defaultHashKey(path string) string {
+	if path == "/" {
+		return "/"
+	}
+	i := strings.LastIndex(path, "/")
+	if i <= 0 {
+		return "/"
+	}
+	return path[:i]
+}
+
+// AddNode registers a backend and rebuilds the hash ring.
+func (s *ShardedClient) AddNode(addr string, rdb *redis.Client) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := New(rdb, s.key)
+	c.SetCommandObserver(s.onCommand)
+	s.backends[addr] = c
+	s.rebuildLocked()
+}
+
+// RemoveNode unregisters a backend and rebuilds the hash ring. The backend's
+// Redis connection is not closed; the caller owns its lifecycle.
+func (s *ShardedClient) RemoveNode(addr string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.backends, addr)
+	s.rebuildLocked()
+}
+
+// SetCommandObserver installs obs on every current and future backend.
+func (s *ShardedClient) SetCommandObserver(obs CommandObserver) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onCommand = obs
+	for _, c := range s.backends {
+		c.SetCommandObserver(obs)
+	}
+}
+
+// rebuildLocked recomputes the Ketama-style ring from the current backend
+// set. Callers must hold s.mu for writing.
+func (s *ShardedClient) rebuildLocked() {
+	ring := make([]uint32, 0, len(s.backends)*vnodesPerBackend)
+	vnodes := make(map[uint32]string, len(s.backends)*vnodesPerBackend)
+	for addr := range s.backends {
+		for i := 0; i < vnodesPerBackend; i++ {
+			sum := sha1.Sum([]byte(addr + "#" + strconv.Itoa(i)))
+			v := binary.BigEndian.Uint32(sum[:4])
+			vnodes[v] = addr
+			ring = append(ring, v)
+		}
+	}
+	sort.Slice(ring, func(i, j int) bool { return ring[i] < ring[j] })
+	s.ring = ring
+	s.vnodes = vnodes
+}
+
+// shardFor returns the backend responsible for path, i.e. the backend
+// holding whatever directory path's parent resolves to via HashKey.
+func (s *ShardedClient) shardFor(path string) (*Client, error) {
+	return s.shardForKey(s.HashKey(path))
+}
+
+// shardForKey returns the backend responsible for the ring key directly,
+// bypassing HashKey. Ls/LsLong use this: they list a directory's own
+// contents, so they must land on the same shard a child's shardFor(child)
+// resolves to, which is the shard for the directory path itself (HashKey of
+// a child is its parent), not the shard for HashKey(path) (the directory's
+// own parent).
+func (s *ShardedClient) shardForKey(key string) (*Client, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.ring) == 0 {
+		return nil, errors.New("client: no shards registered")
+	}
+	h := crc32.ChecksumIEEE([]byte(key))
+	i := sort.Search(len(s.ring), func(i int) bool { return s.ring[i] >= h })
+	if i == len(s.ring) {
+		i = 0
+	}
+	return s.backends[s.vnodes[s.ring[i]]], nil
+}
+
+// sameShard reports whether a and b hash to the same backend.
+func (s *ShardedClient) sameShard(a, b string) (bool, error) {
+	ca, err := s.shardFor(a)
+	if err != nil {
+		return false, err
+	}
+	cb, err := s.shardFor(b)
+	if err != nil {
+		return false, err
+	}
+	return ca == cb, nil
+}
+
+// Stat returns metadata for a path. Returns nil, nil if path does not exist.
+func (s *ShardedClient) Stat(ctx context.Context, path string) (*StatResult, error) {
+	c, err := s.shardFor(path)
+	if err != nil {
+		return nil, err
+	}
+	return c.Stat(ctx, path)
+}
+
+// Cat returns the file content at path.
+func (s *ShardedClient) Cat(ctx context.Context, path string) ([]byte, error) {
+	c, err := s.shardFor(path)
+	if err != nil {
+		return nil, err
+	}
+	return c.Cat(ctx, path)
+}
+
+// Echo writes content to a file (creates or overwrites).
+func (s *ShardedClient) Echo(ctx context.Context, path string, data []byte) error {
+	c, err := s.shardFor(path)
+	if err != nil {
+		return err
+	}
+	return c.Echo(ctx, path, data)
+}
+
+// EchoAppend appends content to a file.
+func (s *ShardedClient) EchoAppend(ctx context.Context, path string, data []byte) error {
+	c, err := s.shardFor(path)
+	if err != nil {
+		return err
+	}
+	return c.EchoAppend(ctx, path, data)
+}
+
+// Touch creates an empty file.
+func (s *ShardedClient) Touch(ctx context.Context, path string) error {
+	c, err := s.shardFor(path)
+	if err != nil {
+		return err
+	}
+	return c.Touch(ctx, path)
+}
+
+// Mkdir creates a directory (with PARENTS to auto-create ancestors).
+func (s *ShardedClient) Mkdir(ctx context.Context, path string) error {
+	c, err := s.shardFor(path)
+	if err != nil {
+		return err
+	}
+	return c.Mkdir(ctx, path)
+}
+
+// Rm removes a file, directory, or symlink.
+func (s *ShardedClient) Rm(ctx context.Context, path string) error {
+	c, err := s.shardFor(path)
+	if err != nil {
+		return err
+	}
+	return c.Rm(ctx, path)
+}
+
+// Ls returns the children of a directory.
+func (s *ShardedClient) Ls(ctx context.Context, path string) ([]string, error) {
+	c, err := s.shardForKey(path)
+	if err != nil {
+		return nil, err
+	}
+	return c.Ls(ctx, path)
+}
+
+// LsLong returns detailed directory listing.
+func (s *ShardedClient) LsLong(ctx context.Context, path string) ([]LsEntry, error) {
+	c, err := s.shardForKey(path)
+	if err != nil {
+		return nil, err
+	}
+	return c.LsLong(ctx, path)
+}
+
+// Mv renames/moves a path. src and dst must hash to the same shard; a
+// cross-shard move would require an atomic move between two independent
+// Redis backends, which FS.MV cannot do, so it's rejected outright rather
+// than silently corrupting one side.
+func (s *ShardedClient) Mv(ctx context.Context, src, dst string) error {
+	same, err := s.sameShard(src, dst)
+	if err != nil {
+		return err
+	}
+	if !same {
+		return fmt.Errorf("client: cross-shard rename %s -> %s not supported", src, dst)
+	}
+	c, err := s.shardFor(src)
+	if err != nil {
+		return err
+	}
+	return c.Mv(ctx, src, dst)
+}
+
+// Ln creates a symbolic link. Only linkpath determines the shard; target is
+// stored verbatim and isn't resolved against the ring.
+func (s *ShardedClient) Ln(ctx context.Context, target, linkpath string) error {
+	c, err := s.shardFor(linkpath)
+	if err != nil {
+		return err
+	}
+	return c.Ln(ctx, target, linkpath)
+}
+
+// Readlink returns the target of a symbolic link.
+func (s *ShardedClient) Readlink(ctx context.Context, path string) (string, error) {
+	c, err := s.shardFor(path)
+	if err != nil {
+		return "", err
+	}
+	return c.Readlink(ctx, path)
+}
+
+// Chmod changes file permissions.
+func (s *ShardedClient) Chmod(ctx context.Context, path string, mode uint32) error {
+	c, err := s.shardFor(path)
+	if err != nil {
+		return err
+	}
+	return c.Chmod(ctx, path, mode)
+}
+
+// Chown changes file owner and group.
+func (s *ShardedClient) Chown(ctx context.Context, path string, uid, gid uint32) error {
+	c, err := s.shardFor(path)
+	if err != nil {
+		return err
+	}
+	return c.Chown(ctx, path, uid, gid)
+}
+
+// Truncate truncates or extends a file to the given length.
+func (s *ShardedClient) Truncate(ctx context.Context, path string, size int64) error {
+	c, err := s.shardFor(path)
+	if err != nil {
+		return err
+	}
+	return c.Truncate(ctx, path, size)
+}
+
+// Utimens sets access and modification times (milliseconds). -1 means don't change.
+func (s *ShardedClient) Utimens(ctx context.Context, path string, atimeMs, mtimeMs int64) error {
+	c, err := s.shardFor(path)
+	if err != nil {
+		return err
+	}
+	return c.Utimens(ctx, path, atimeMs, mtimeMs)
+}
+
+// GetXattr returns the value of an extended attribute, or nil if it isn't set.
+func (s *ShardedClient) GetXattr(ctx context.Context, path, name string) ([]byte, error) {
+	c, err := s.shardFor(path)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetXattr(ctx, path, name)
+}
+
+// SetXattr sets an extended attribute on path.
+func (s *ShardedClient) SetXattr(ctx context.Context, path, name string, value []byte) error {
+	c, err := s.shardFor(path)
+	if err != nil {
+		return err
+	}
+	return c.SetXattr(ctx, path, name, value)
+}
+
+// ListXattr returns the names of the extended attributes set on path.
+func (s *ShardedClient) ListXattr(ctx context.Context, path string) ([]string, error) {
+	c, err := s.shardFor(path)
+	if err != nil {
+		return nil, err
+	}
+	return c.ListXattr(ctx, path)
+}
+
+// RemoveXattr removes an extended attribute from path.
+func (s *ShardedClient) RemoveXattr(ctx context.Context, path, name string) error {
+	c, err := s.shardFor(path)
+	if err != nil {
+		return err
+	}
+	return c.RemoveXattr(ctx, path, name)
+}
+
+// Info returns filesystem-level statistics, summed across every backend.
+func (s *ShardedClient) Info(ctx context.Context) (*InfoResult, error) {
+	s.mu.RLock()
+	backends := make([]*Client, 0, len(s.backends))
+	for _, c := range s.backends {
+		backends = append(backends, c)
+	}
+	s.mu.RUnlock()
+
+	if len(backends) == 0 {
+		return nil, errors.New("client: no shards registered")
+	}
+
+	total := &InfoResult{RangeSupport: true}
+	for _, c := range backends {
+		info, err := c.Info(ctx)
+		if err != nil {
+			return nil, err
+		}
+		total.Files += info.Files
+		total.Directories += info.Directories
+		total.Symlinks += info.Symlinks
+		total.TotalDataBytes += info.TotalDataBytes
+		total.TotalInodes += info.TotalInodes
+		total.RangeSupport = total.RangeSupport && info.RangeSupport
+	}
+	return total, nil
+}
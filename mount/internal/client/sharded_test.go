@@ -0,0 +1,132 @@
+package client
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func newFakeBackend(addr string) *redis.Client {
+	return redis.NewClient(&redis.Options{Addr: addr})
+}
+
+func TestShardedClientColocatesDirectoryChildren(t *testing.T) {
+	s := NewSharded("fs")
+	for _, addr := range []string{"a:6379", "b:6379", "c:6379"} {
+		s.AddNode(addr, newFakeBackend(addr))
+	}
+
+	dir, err := s.shardFor("/photos")
+	if err != nil {
+		t.Fatalf("shardFor(/photos): %v", err)
+	}
+	for _, child := range []string{"/photos/a.jpg", "/photos/b.jpg", "/photos/sub/c.jpg"} {
+		c, err := s.shardFor(child)
+		if err != nil {
+			t.Fatalf("shardFor(%s): %v", child, err)
+		}
+		if child == "/photos/sub/c.jpg" {
+			continue // different parent dir, may land elsewhere
+		}
+		if c != dir {
+			t.Fatalf("shardFor(%s) = %p, want same shard as /photos (%p)", child, c, dir)
+		}
+	}
+}
+
+// TestShardedClientLsRoutesToSameShardAsChildren guards against Ls/LsLong
+// resolving a directory to a different backend than the one its children
+// actually live on. A child's own shardFor(child) hashes HashKey(child),
+// which is the child's parent directory, i.e. path itself — so listing path
+// must route the same way, not through another HashKey(path) indirection
+// (which would instead land on path's own parent).
+func TestShardedClientLsRoutesToSameShardAsChildren(t *testing.T) {
+	s := NewSharded("fs")
+	for _, addr := range []string{"a:6379", "b:6379", "c:6379", "d:6379", "e:6379"} {
+		s.AddNode(addr, newFakeBackend(addr))
+	}
+
+	for _, dir := range []string{"/photos", "/photos/sub", "/a/b/c", "/"} {
+		lsShard, err := s.shardForKey(dir)
+		if err != nil {
+			t.Fatalf("shardForKey(%s): %v", dir, err)
+		}
+		child := dir + "/child.jpg"
+		if dir == "/" {
+			child = "/child.jpg"
+		}
+		childShard, err := s.shardFor(child)
+		if err != nil {
+			t.Fatalf("shardFor(%s): %v", child, err)
+		}
+		if lsShard != childShard {
+			t.Fatalf("Ls(%s) would route to shard %p, but its child %s is stored on shard %p", dir, lsShard, child, childShard)
+		}
+	}
+}
+
+func TestShardedClientRemoveNodeRebalances(t *testing.T) {
+	s := NewSharded("fs")
+	s.AddNode("a:6379", newFakeBackend("a:6379"))
+	s.AddNode("b:6379", newFakeBackend("b:6379"))
+
+	if len(s.ring) != 2*vnodesPerBackend {
+		t.Fatalf("ring size = %d, want %d", len(s.ring), 2*vnodesPerBackend)
+	}
+
+	s.RemoveNode("a:6379")
+	if len(s.ring) != vnodesPerBackend {
+		t.Fatalf("ring size after remove = %d, want %d", len(s.ring), vnodesPerBackend)
+	}
+	for _, addr := range s.vnodes {
+		if addr != "b:6379" {
+			t.Fatalf("vnode pointing at removed backend: %s", addr)
+		}
+	}
+}
+
+func TestShardedClientNoNodesReturnsError(t *testing.T) {
+	s := NewSharded("fs")
+	if _, err := s.shardFor("/x"); err == nil {
+		t.Fatal("expected error with no shards registered")
+	}
+}
+
+func TestShardedClientMvCrossShardReturnsError(t *testing.T) {
+	s := NewSharded("fs")
+	for _, addr := range []string{"a:6379", "b:6379", "c:6379", "d:6379"} {
+		s.AddNode(addr, newFakeBackend(addr))
+	}
+
+	// Find two paths that land on different shards.
+	var src, dst string
+	for _, candidate := range []string{"/one/x", "/two/x", "/three/x", "/four/x", "/five/x"} {
+		c, err := s.shardFor(candidate)
+		if err != nil {
+			t.Fatalf("shardFor(%s): %v", candidate, err)
+		}
+		if src == "" {
+			src = candidate
+			continue
+		}
+		first, _ := s.shardFor(src)
+		if c != first {
+			dst = candidate
+			break
+		}
+	}
+	if dst == "" {
+		t.Skip("could not find two paths landing on different shards")
+	}
+
+	err := s.Mv(context.Background(), src, dst)
+	if err == nil {
+		t.Fatalf("expected error moving %s -> %s across shards", src, dst)
+	}
+	// redisfs.mapError maps this substring to syscall.EXDEV.
+	if !strings.Contains(err.Error(), "cross-shard") {
+		t.Fatalf("error = %v, want it to mention cross-shard", err)
+	}
+}
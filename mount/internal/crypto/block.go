@@ -0,0 +1,91 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// BlockSize is the plaintext granularity content is encrypted in. It evenly
+// divides the FileHandle chunk size the FUSE layer reads/writes in, so a
+// chunk-aligned Read/WriteRange always maps to a whole number of cipher
+// blocks with no partial-block merge required.
+const BlockSize = 4096
+
+// blockNonceSize and blockTagSize make up the 32 bytes of per-block
+// overhead: a random 16-byte nonce (so repeated writes to the same block
+// don't reuse a nonce under the same key) and a 16-byte GCM tag.
+const (
+	blockNonceSize = 16
+	blockTagSize   = 16
+	// BlockOverhead is the number of ciphertext bytes a block carries beyond
+	// its plaintext: a 16-byte nonce plus a 16-byte GCM tag.
+	BlockOverhead = blockNonceSize + blockTagSize
+)
+
+// Cryptor performs the actual block and filename encryption/decryption for
+// one mount, given its derived Keys.
+type Cryptor struct {
+	content cipher.AEAD
+	names   cipher.AEAD
+}
+
+// NewCryptor builds the AES-GCM (content) and AES-SIV (names) ciphers from
+// keys.
+func NewCryptor(keys *Keys) (*Cryptor, error) {
+	block, err := aes.NewCipher(keys.Content[:])
+	if err != nil {
+		return nil, fmt.Errorf("crypto: content cipher: %w", err)
+	}
+	content, err := cipher.NewGCMWithNonceSize(block, blockNonceSize)
+	if err != nil {
+		return nil, fmt.Errorf("crypto: content AEAD: %w", err)
+	}
+	names, err := newSIV(keys.Name[:])
+	if err != nil {
+		return nil, fmt.Errorf("crypto: name AEAD: %w", err)
+	}
+	return &Cryptor{content: content, names: names}, nil
+}
+
+// EncryptBlock encrypts one plaintext block (at most BlockSize bytes) as it
+// will be stored: a fresh random nonce, then the GCM-sealed ciphertext+tag.
+// idx (the block's position within the file) is bound in as additional
+// data, so ciphertext blocks can't be silently reordered or spliced between
+// files without detection on decrypt.
+func (c *Cryptor) EncryptBlock(plaintext []byte, idx uint64) ([]byte, error) {
+	nonce := make([]byte, blockNonceSize)
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("crypto: generating block nonce: %w", err)
+	}
+	out := make([]byte, blockNonceSize, blockNonceSize+len(plaintext)+blockTagSize)
+	copy(out, nonce)
+	return c.content.Seal(out, nonce, plaintext, blockAAD(idx)), nil
+}
+
+// DecryptBlock reverses EncryptBlock, verifying idx matches the value
+// supplied at encryption time.
+func (c *Cryptor) DecryptBlock(ciphertext []byte, idx uint64) ([]byte, error) {
+	if len(ciphertext) < blockNonceSize+blockTagSize {
+		return nil, fmt.Errorf("crypto: block %d too short (%d bytes)", idx, len(ciphertext))
+	}
+	nonce := ciphertext[:blockNonceSize]
+	sealed := ciphertext[blockNonceSize:]
+	return c.content.Open(nil, nonce, sealed, blockAAD(idx))
+}
+
+// blockAAD encodes a block index as GCM additional authenticated data.
+func blockAAD(idx uint64) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], idx)
+	return buf[:]
+}
+
+// CipherBlockSize returns the on-disk size of a full block holding
+// plaintextLen plaintext bytes (plaintextLen must be <= BlockSize).
+func CipherBlockSize(plaintextLen int) int {
+	return plaintextLen + BlockOverhead
+}
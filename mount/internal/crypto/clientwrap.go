@@ -0,0 +1,262 @@
+package crypto
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/redis-fs/mount/internal/client"
+)
+
+// Client wraps a *client.Client to make file content transparently
+// encrypted at rest: every content-bearing method (Cat, Read, WriteRange,
+// Echo, EchoAppend) encrypts/decrypts in fixed BlockSize blocks, and Stat,
+// Size, and Truncate translate between the plaintext size FUSE callers
+// expect and the larger ciphertext size actually stored in Redis. Every
+// other method (Mkdir, Rm, Mv, xattrs, transactions, ...) is unaffected by
+// encryption and passes straight through to the embedded Client.
+//
+// Filename/path encryption is deliberately not done here: callers (FSNode,
+// FileHandle) are expected to already have translated path into its
+// encrypted form via Cryptor.EncryptPath before calling any method on
+// Client, so the go-fuse layer's path-handling code stays the single place
+// that knows about cleartext vs. encrypted names.
+type Client struct {
+	*client.Client
+	cryptor *Cryptor
+}
+
+// Wrap returns c with content transparently encrypted using cryptor.
+func Wrap(c *client.Client, cryptor *Cryptor) *Client {
+	return &Client{Client: c, cryptor: cryptor}
+}
+
+// Cryptor returns the Cryptor backing this Client, for callers (FSNode) that
+// need it directly for path/name translation.
+func (w *Client) Cryptor() *Cryptor {
+	return w.cryptor
+}
+
+// plainToCipherSize converts a plaintext byte count to the number of
+// ciphertext bytes it's stored as.
+func plainToCipherSize(n int64) int64 {
+	if n <= 0 {
+		return n
+	}
+	full := n / BlockSize
+	rem := n % BlockSize
+	size := full * int64(CipherBlockSize(BlockSize))
+	if rem > 0 {
+		size += int64(CipherBlockSize(int(rem)))
+	}
+	return size
+}
+
+// cipherToPlainSize is the inverse of plainToCipherSize.
+func cipherToPlainSize(n int64) int64 {
+	if n <= 0 {
+		return n
+	}
+	fullCipherBlock := int64(CipherBlockSize(BlockSize))
+	full := n / fullCipherBlock
+	rem := n % fullCipherBlock
+	size := full * BlockSize
+	if rem > 0 {
+		size -= BlockOverhead
+	}
+	return size
+}
+
+// Stat overrides Client.Stat to report the plaintext size of a file instead
+// of the larger ciphertext size actually stored.
+func (w *Client) Stat(ctx context.Context, path string) (*client.StatResult, error) {
+	st, err := w.Client.Stat(ctx, path)
+	if err != nil || st == nil || st.Type != "file" {
+		return st, err
+	}
+	st.Size = cipherToPlainSize(st.Size)
+	return st, nil
+}
+
+// Size overrides Client.Size to report the plaintext size.
+func (w *Client) Size(ctx context.Context, path string) (int64, error) {
+	size, err := w.Client.Size(ctx, path)
+	if err != nil || size < 0 {
+		return size, err
+	}
+	return cipherToPlainSize(size), nil
+}
+
+// encryptBlocks encrypts data as a sequence of BlockSize plaintext blocks
+// starting at block index startBlock.
+func (w *Client) encryptBlocks(data []byte, startBlock uint64) ([]byte, error) {
+	out := make([]byte, 0, len(data)+(len(data)/BlockSize+1)*BlockOverhead)
+	for off := 0; off < len(data); off += BlockSize {
+		end := off + BlockSize
+		if end > len(data) {
+			end = len(data)
+		}
+		ct, err := w.cryptor.EncryptBlock(data[off:end], startBlock+uint64(off/BlockSize))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, ct...)
+	}
+	return out, nil
+}
+
+// decryptBlocks reverses encryptBlocks; ciphertext must be a concatenation
+// of whole blocks (as produced by encryptBlocks/WriteRange).
+func (w *Client) decryptBlocks(ciphertext []byte, startBlock uint64) ([]byte, error) {
+	out := make([]byte, 0, len(ciphertext))
+	blockIdx := startBlock
+	for off := 0; off < len(ciphertext); {
+		end := off + CipherBlockSize(BlockSize)
+		if end > len(ciphertext) {
+			end = len(ciphertext)
+		}
+		pt, err := w.cryptor.DecryptBlock(ciphertext[off:end], blockIdx)
+		if err != nil {
+			return nil, fmt.Errorf("crypto: decrypting block %d: %w", blockIdx, err)
+		}
+		out = append(out, pt...)
+		off = end
+		blockIdx++
+	}
+	return out, nil
+}
+
+// Cat decrypts the whole file content.
+func (w *Client) Cat(ctx context.Context, path string) ([]byte, error) {
+	ciphertext, err := w.Client.Cat(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return w.decryptBlocks(ciphertext, 0)
+}
+
+// Echo encrypts data and overwrites the whole file.
+func (w *Client) Echo(ctx context.Context, path string, data []byte) error {
+	ciphertext, err := w.encryptBlocks(data, 0)
+	if err != nil {
+		return err
+	}
+	return w.Client.Echo(ctx, path, ciphertext)
+}
+
+// EchoAppend appends data by decrypting the current content, appending in
+// plaintext, and re-encrypting the whole file. Unlike the plaintext FS.ECHO
+// ... APPEND this isn't a cheap server-side append, since re-sealing the
+// previous last block (whose tag covered exactly its old length) requires
+// its plaintext; this is an acceptable cost since FileHandle always opens
+// files with O_TRUNC or writes via WriteRange rather than append mode.
+func (w *Client) EchoAppend(ctx context.Context, path string, data []byte) error {
+	cur, err := w.Cat(ctx, path)
+	if err != nil {
+		if !strings.Contains(err.Error(), "no such") {
+			return err
+		}
+		cur = nil
+	}
+	return w.Echo(ctx, path, append(cur, data...))
+}
+
+// Read decrypts the blocks covering [offset, offset+length). offset must be
+// BlockSize-aligned; FileHandle's chunked I/O (chunkSize is a multiple of
+// BlockSize) guarantees this.
+func (w *Client) Read(ctx context.Context, path string, offset, length int64) ([]byte, error) {
+	if offset%BlockSize != 0 {
+		return nil, fmt.Errorf("crypto: Read offset %d is not block-aligned", offset)
+	}
+	startBlock := uint64(offset / BlockSize)
+	numBlocks := (length + BlockSize - 1) / BlockSize
+
+	cipherSize, err := w.Client.Size(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	cipherOffset := startBlock * uint64(CipherBlockSize(BlockSize))
+	cipherLength := numBlocks * int64(CipherBlockSize(BlockSize))
+	if int64(cipherOffset)+cipherLength > cipherSize {
+		cipherLength = cipherSize - int64(cipherOffset)
+	}
+	if cipherLength <= 0 {
+		return nil, nil
+	}
+
+	ciphertext, err := w.Client.Read(ctx, path, int64(cipherOffset), cipherLength)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := w.decryptBlocks(ciphertext, startBlock)
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(plain)) > length {
+		plain = plain[:length]
+	}
+	return plain, nil
+}
+
+// WriteRange encrypts data as whole blocks starting at offset and writes
+// them in place. offset must be BlockSize-aligned, as with Read.
+func (w *Client) WriteRange(ctx context.Context, path string, offset int64, data []byte) error {
+	if offset%BlockSize != 0 {
+		return fmt.Errorf("crypto: WriteRange offset %d is not block-aligned", offset)
+	}
+	startBlock := uint64(offset / BlockSize)
+	ciphertext, err := w.encryptBlocks(data, startBlock)
+	if err != nil {
+		return err
+	}
+	cipherOffset := int64(startBlock) * int64(CipherBlockSize(BlockSize))
+	return w.Client.WriteRange(ctx, path, cipherOffset, ciphertext)
+}
+
+// Truncate shrinks or extends a file to size plaintext bytes, re-encrypting
+// the block straddling the new boundary so the WriteRange/Client.Truncate
+// calls that implement it stay block-aligned.
+func (w *Client) Truncate(ctx context.Context, path string, size int64) error {
+	curSize, err := w.Size(ctx, path)
+	if err != nil {
+		return err
+	}
+	if curSize < 0 {
+		curSize = 0
+	}
+
+	if size > curSize {
+		// WriteRange requires a block-aligned offset, but curSize usually
+		// isn't one: re-read the (possibly partial) last existing block and
+		// rewrite it together with the new zero-filled tail in one
+		// block-aligned call.
+		blockStart := (curSize / BlockSize) * BlockSize
+		existing, err := w.Read(ctx, path, blockStart, curSize-blockStart)
+		if err != nil {
+			return err
+		}
+		grown := make([]byte, size-blockStart)
+		copy(grown, existing)
+		return w.WriteRange(ctx, path, blockStart, grown)
+	}
+
+	targetBlocks := size / BlockSize
+	rem := size % BlockSize
+	cipherTruncateAt := targetBlocks * int64(CipherBlockSize(BlockSize))
+
+	if rem > 0 {
+		block, err := w.Read(ctx, path, targetBlocks*BlockSize, BlockSize)
+		if err != nil {
+			return err
+		}
+		if int64(len(block)) > rem {
+			block = block[:rem]
+		}
+		if err := w.WriteRange(ctx, path, targetBlocks*BlockSize, block); err != nil {
+			return err
+		}
+		cipherTruncateAt += int64(CipherBlockSize(len(block)))
+	}
+
+	return w.Client.Truncate(ctx, path, cipherTruncateAt)
+}
@@ -0,0 +1,155 @@
+package crypto
+
+import "testing"
+
+func testCryptor(t *testing.T) *Cryptor {
+	t.Helper()
+	cfg, err := GenerateConfig()
+	if err != nil {
+		t.Fatalf("GenerateConfig: %v", err)
+	}
+	keys, err := DeriveKeys([]byte("hunter2"), cfg)
+	if err != nil {
+		t.Fatalf("DeriveKeys: %v", err)
+	}
+	c, err := NewCryptor(keys)
+	if err != nil {
+		t.Fatalf("NewCryptor: %v", err)
+	}
+	return c
+}
+
+func TestBlockRoundTrip(t *testing.T) {
+	c := testCryptor(t)
+
+	plain := []byte("the quick brown fox jumps over the lazy dog")
+	ct, err := c.EncryptBlock(plain, 7)
+	if err != nil {
+		t.Fatalf("EncryptBlock: %v", err)
+	}
+	if len(ct) != CipherBlockSize(len(plain)) {
+		t.Fatalf("ciphertext len = %d, want %d", len(ct), CipherBlockSize(len(plain)))
+	}
+
+	pt, err := c.DecryptBlock(ct, 7)
+	if err != nil {
+		t.Fatalf("DecryptBlock: %v", err)
+	}
+	if string(pt) != string(plain) {
+		t.Fatalf("DecryptBlock = %q, want %q", pt, plain)
+	}
+}
+
+func TestBlockWrongIndexFails(t *testing.T) {
+	c := testCryptor(t)
+
+	ct, err := c.EncryptBlock([]byte("block zero"), 0)
+	if err != nil {
+		t.Fatalf("EncryptBlock: %v", err)
+	}
+	if _, err := c.DecryptBlock(ct, 1); err == nil {
+		t.Fatal("DecryptBlock with wrong block index should fail")
+	}
+}
+
+func TestBlockEncryptionIsRandomized(t *testing.T) {
+	c := testCryptor(t)
+
+	a, err := c.EncryptBlock([]byte("same plaintext"), 0)
+	if err != nil {
+		t.Fatalf("EncryptBlock: %v", err)
+	}
+	b, err := c.EncryptBlock([]byte("same plaintext"), 0)
+	if err != nil {
+		t.Fatalf("EncryptBlock: %v", err)
+	}
+	if string(a) == string(b) {
+		t.Fatal("two encryptions of the same block produced identical ciphertext (nonce reuse?)")
+	}
+}
+
+func TestNameRoundTrip(t *testing.T) {
+	c := testCryptor(t)
+
+	for _, name := range []string{"foo", "some file.txt", "a", "unicode-éè"} {
+		enc := c.EncryptName(name)
+		dec, err := c.DecryptName(enc)
+		if err != nil {
+			t.Fatalf("DecryptName(%q): %v", name, err)
+		}
+		if dec != name {
+			t.Fatalf("round trip of %q = %q", name, dec)
+		}
+	}
+}
+
+func TestNameEncryptionIsDeterministic(t *testing.T) {
+	c := testCryptor(t)
+
+	a := c.EncryptName("repeated")
+	b := c.EncryptName("repeated")
+	if a != b {
+		t.Fatalf("EncryptName not deterministic: %q != %q", a, b)
+	}
+}
+
+func TestPathRoundTrip(t *testing.T) {
+	c := testCryptor(t)
+
+	if got := c.EncryptPath("/"); got != "/" {
+		t.Fatalf("EncryptPath(/) = %q, want /", got)
+	}
+
+	enc := c.EncryptPath("/foo/bar/baz.txt")
+	dec, err := c.DecryptPath(enc)
+	if err != nil {
+		t.Fatalf("DecryptPath: %v", err)
+	}
+	if dec != "/foo/bar/baz.txt" {
+		t.Fatalf("DecryptPath round trip = %q", dec)
+	}
+}
+
+func TestDeriveKeysDeterministic(t *testing.T) {
+	cfg, err := GenerateConfig()
+	if err != nil {
+		t.Fatalf("GenerateConfig: %v", err)
+	}
+	a, err := DeriveKeys([]byte("correct horse battery staple"), cfg)
+	if err != nil {
+		t.Fatalf("DeriveKeys: %v", err)
+	}
+	b, err := DeriveKeys([]byte("correct horse battery staple"), cfg)
+	if err != nil {
+		t.Fatalf("DeriveKeys: %v", err)
+	}
+	if a.Content != b.Content || a.Name != b.Name {
+		t.Fatal("DeriveKeys with the same passphrase/config produced different keys")
+	}
+
+	wrong, err := DeriveKeys([]byte("wrong passphrase"), cfg)
+	if err != nil {
+		t.Fatalf("DeriveKeys: %v", err)
+	}
+	if a.Content == wrong.Content {
+		t.Fatal("DeriveKeys with a different passphrase produced the same content key")
+	}
+}
+
+func TestConfigMarshalRoundTrip(t *testing.T) {
+	cfg, err := GenerateConfig()
+	if err != nil {
+		t.Fatalf("GenerateConfig: %v", err)
+	}
+	data, err := cfg.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	got, err := ParseConfig(data)
+	if err != nil {
+		t.Fatalf("ParseConfig: %v", err)
+	}
+	if string(got.Salt) != string(cfg.Salt) || got.ScryptN != cfg.ScryptN {
+		t.Fatalf("ParseConfig round trip mismatch: %+v != %+v", got, cfg)
+	}
+}
@@ -0,0 +1,106 @@
+// Package crypto implements an optional gocryptfs-style encryption-at-rest
+// layer: file content is encrypted with AES-GCM in fixed-size blocks, and
+// filenames/symlink targets are encrypted with AES-SIV (deterministic, so
+// the same cleartext name always maps to the same ciphertext) and
+// base64url-encoded. The master key is derived from a passphrase with
+// scrypt; everything needed to redo that derivation (salt, scrypt cost
+// parameters) is stored, unencrypted, in Config.
+package crypto
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/scrypt"
+)
+
+// contentKeyLen is the AES-256-GCM key size.
+const contentKeyLen = 32
+
+// nameKeyLen is the AES-SIV-CMAC key size; per RFC 5297 it must be twice the
+// underlying AES key size, so 64 bytes selects AES-256-SIV.
+const nameKeyLen = 64
+
+// saltLen is the scrypt salt size.
+const saltLen = 16
+
+// Default scrypt cost parameters, matching gocryptfs' defaults: strong
+// enough to be expensive to brute-force offline, cheap enough to run once
+// per mount.
+const (
+	DefaultScryptN = 1 << 16
+	DefaultScryptR = 8
+	DefaultScryptP = 1
+)
+
+// Config is the on-disk (stored, unencrypted, under "<fskey>:cryptoconf")
+// record of how to re-derive Keys from a passphrase. It contains no secret
+// material itself.
+type Config struct {
+	Salt    []byte `json:"salt"`
+	ScryptN int    `json:"scrypt_n"`
+	ScryptR int    `json:"scrypt_r"`
+	ScryptP int    `json:"scrypt_p"`
+}
+
+// GenerateConfig creates a new Config with a random salt and the default
+// scrypt cost parameters.
+func GenerateConfig() (*Config, error) {
+	salt := make([]byte, saltLen)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, fmt.Errorf("crypto: generating salt: %w", err)
+	}
+	return &Config{
+		Salt:    salt,
+		ScryptN: DefaultScryptN,
+		ScryptR: DefaultScryptR,
+		ScryptP: DefaultScryptP,
+	}, nil
+}
+
+// Marshal serializes c for storage at "<fskey>:cryptoconf".
+func (c *Config) Marshal() ([]byte, error) {
+	return json.Marshal(c)
+}
+
+// ParseConfig deserializes a Config previously written by Marshal.
+func ParseConfig(data []byte) (*Config, error) {
+	var c Config
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("crypto: parsing cryptoconf: %w", err)
+	}
+	return &c, nil
+}
+
+// Keys holds the derived, independent content- and filename-encryption
+// keys. The two are kept separate (via HKDF, not just split in half) so a
+// cryptanalytic weakness found in one scheme can't be leveraged against the
+// other.
+type Keys struct {
+	Content [contentKeyLen]byte
+	Name    [nameKeyLen]byte
+}
+
+// DeriveKeys stretches passphrase with scrypt per c's parameters, then
+// expands the result into Keys.Content and Keys.Name with HKDF-SHA256.
+func DeriveKeys(passphrase []byte, c *Config) (*Keys, error) {
+	master, err := scrypt.Key(passphrase, c.Salt, c.ScryptN, c.ScryptR, c.ScryptP, sha256.Size)
+	if err != nil {
+		return nil, fmt.Errorf("crypto: deriving master key: %w", err)
+	}
+
+	keys := &Keys{}
+	content := hkdf.New(sha256.New, master, c.Salt, []byte("redis-fs content"))
+	if _, err := io.ReadFull(content, keys.Content[:]); err != nil {
+		return nil, fmt.Errorf("crypto: deriving content key: %w", err)
+	}
+	name := hkdf.New(sha256.New, master, c.Salt, []byte("redis-fs names"))
+	if _, err := io.ReadFull(name, keys.Name[:]); err != nil {
+		return nil, fmt.Errorf("crypto: deriving name key: %w", err)
+	}
+	return keys, nil
+}
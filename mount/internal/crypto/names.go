@@ -0,0 +1,70 @@
+package crypto
+
+import (
+	"crypto/cipher"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	siv "github.com/secure-io/siv-go"
+)
+
+// newSIV builds the deterministic AEAD used for filenames: AES-SIV-CMAC
+// (RFC 5297), called with a nil nonce so the same name always encrypts to
+// the same ciphertext. That determinism is required here (not a weakness):
+// Lookup needs to re-derive the same ciphertext name from a cleartext path
+// component to find it in Redis, without a stored per-name nonce to consult.
+func newSIV(key []byte) (cipher.AEAD, error) {
+	return siv.NewCMAC(key)
+}
+
+// EncryptName deterministically encrypts a single path component (a
+// filename, not a full path) and base64url-encodes it so the result is safe
+// to pass to FS.LS/FS.STAT/etc. as a plain path segment.
+func (c *Cryptor) EncryptName(name string) string {
+	sealed := c.names.Seal(nil, nil, []byte(name), nil)
+	return base64.RawURLEncoding.EncodeToString(sealed)
+}
+
+// DecryptName reverses EncryptName.
+func (c *Cryptor) DecryptName(encoded string) (string, error) {
+	sealed, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("crypto: decoding name: %w", err)
+	}
+	plain, err := c.names.Open(nil, nil, sealed, nil)
+	if err != nil {
+		return "", fmt.Errorf("crypto: decrypting name: %w", err)
+	}
+	return string(plain), nil
+}
+
+// EncryptPath encrypts every "/"-separated component of an absolute path
+// independently (gocryptfs-style), so the tree shape is preserved in Redis
+// while every individual name is opaque ciphertext. "/" maps to itself.
+func (c *Cryptor) EncryptPath(path string) string {
+	if path == "/" {
+		return "/"
+	}
+	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	for i, p := range parts {
+		parts[i] = c.EncryptName(p)
+	}
+	return "/" + strings.Join(parts, "/")
+}
+
+// DecryptPath reverses EncryptPath.
+func (c *Cryptor) DecryptPath(path string) (string, error) {
+	if path == "/" {
+		return "/", nil
+	}
+	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	for i, p := range parts {
+		name, err := c.DecryptName(p)
+		if err != nil {
+			return "", err
+		}
+		parts[i] = name
+	}
+	return "/" + strings.Join(parts, "/"), nil
+}
@@ -25,15 +25,15 @@ func statToAttr(st *client.StatResult, uid, gid uint32) fuse.Attr {
 	}
 
 	attr := fuse.Attr{
-		Mode:  mode,
-		Nlink: nlink,
-		Size:  uint64(st.Size),
-		Owner: fuse.Owner{Uid: uid, Gid: gid},
-		Atime: uint64(st.Atime / 1000),
+		Mode:      mode,
+		Nlink:     nlink,
+		Size:      uint64(st.Size),
+		Owner:     fuse.Owner{Uid: uid, Gid: gid},
+		Atime:     uint64(st.Atime / 1000),
 		Atimensec: uint32((st.Atime % 1000) * 1_000_000),
-		Mtime: uint64(st.Mtime / 1000),
+		Mtime:     uint64(st.Mtime / 1000),
 		Mtimensec: uint32((st.Mtime % 1000) * 1_000_000),
-		Ctime: uint64(st.Ctime / 1000),
+		Ctime:     uint64(st.Ctime / 1000),
 		Ctimensec: uint32((st.Ctime % 1000) * 1_000_000),
 	}
 
@@ -0,0 +1,48 @@
+package redisfs
+
+import (
+	"context"
+
+	"github.com/redis-fs/mount/internal/client"
+)
+
+// fsClient is the subset of *client.Client's method set that FSNode and
+// FileHandle call against. It exists so a mount can optionally be backed by
+// *crypto.Client (which wraps a *client.Client to transparently encrypt
+// file content) instead of talking to Redis directly, without FSNode or
+// FileHandle needing to know which one they have.
+type fsClient interface {
+	BeginTxn(ctx context.Context) (string, error)
+	CommitTxn(ctx context.Context) error
+	AbortTxn(ctx context.Context) error
+	ActiveTxn() string
+
+	Stat(ctx context.Context, path string) (*client.StatResult, error)
+	Cat(ctx context.Context, path string) ([]byte, error)
+	Read(ctx context.Context, path string, offset, length int64) ([]byte, error)
+	WriteRange(ctx context.Context, path string, offset int64, data []byte) error
+	Size(ctx context.Context, path string) (int64, error)
+	Echo(ctx context.Context, path string, data []byte) error
+	EchoAppend(ctx context.Context, path string, data []byte) error
+	Touch(ctx context.Context, path string) error
+	Mkdir(ctx context.Context, path string) error
+	Rm(ctx context.Context, path string) error
+	Ls(ctx context.Context, path string) ([]string, error)
+	LsLong(ctx context.Context, path string) ([]client.LsEntry, error)
+	Mv(ctx context.Context, src, dst string) error
+	MvNoReplace(ctx context.Context, src, dst string) error
+	MvExchange(ctx context.Context, a, b string) error
+	Ln(ctx context.Context, target, linkpath string) error
+	Readlink(ctx context.Context, path string) (string, error)
+	Chmod(ctx context.Context, path string, mode uint32) error
+	Chown(ctx context.Context, path string, uid, gid uint32) error
+	Truncate(ctx context.Context, path string, size int64) error
+	Utimens(ctx context.Context, path string, atimeMs, mtimeMs int64) error
+	GetXattr(ctx context.Context, path, name string) ([]byte, error)
+	SetXattr(ctx context.Context, path, name string, value []byte) error
+	ListXattr(ctx context.Context, path string) ([]string, error)
+	RemoveXattr(ctx context.Context, path, name string) error
+	Info(ctx context.Context) (*client.InfoResult, error)
+
+	NewBatch(ctx context.Context) *client.Batch
+}
@@ -0,0 +1,119 @@
+package redisfs
+
+import (
+	"context"
+	"strings"
+	"syscall"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// controlDirName is a synthetic top-level directory exposing transaction
+// control files. It is not backed by Redis, has no entry in FS.LS output,
+// and only intercepts Lookup on the mount root.
+const controlDirName = ".redisfs"
+
+// controlTxnFileName is the control file that starts, commits, or aborts a
+// whole-mount transaction: `echo begin > .redisfs/txn`, then `echo commit >
+// .redisfs/txn` (or `echo abort > .redisfs/txn` to discard). Reading it
+// reports the active transaction id, or "idle".
+const controlTxnFileName = "txn"
+
+// controlDirNode is the ".redisfs" directory.
+type controlDirNode struct {
+	fs.Inode
+	root *FSRoot
+}
+
+// Lookup implements fs.NodeLookuper.
+func (d *controlDirNode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	if name != controlTxnFileName {
+		return nil, syscall.ENOENT
+	}
+	return d.NewInode(ctx, &controlTxnNode{root: d.root}, fs.StableAttr{Mode: syscall.S_IFREG}), 0
+}
+
+// Readdir implements fs.NodeReaddirer.
+func (d *controlDirNode) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
+	return fs.NewListDirStream([]fuse.DirEntry{
+		{Name: controlTxnFileName, Mode: syscall.S_IFREG},
+	}), 0
+}
+
+// Getattr implements fs.NodeGetattrer.
+func (d *controlDirNode) Getattr(ctx context.Context, fh fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	out.Mode = syscall.S_IFDIR | 0755
+	out.Nlink = 2
+	return 0
+}
+
+var _ fs.NodeLookuper = (*controlDirNode)(nil)
+var _ fs.NodeReaddirer = (*controlDirNode)(nil)
+var _ fs.NodeGetattrer = (*controlDirNode)(nil)
+
+// controlTxnNode is the ".redisfs/txn" control file.
+type controlTxnNode struct {
+	fs.Inode
+	root *FSRoot
+}
+
+// Getattr implements fs.NodeGetattrer.
+func (n *controlTxnNode) Getattr(ctx context.Context, fh fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	out.Mode = syscall.S_IFREG | 0644
+	out.Size = uint64(len(n.root.txnStatus()))
+	return 0
+}
+
+// Open implements fs.NodeOpener. FOPEN_DIRECT_IO tells the kernel this
+// file's content is generated per-read rather than page-cached, matching
+// its /proc-style "status changes on each read" semantics.
+func (n *controlTxnNode) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
+	return &controlTxnHandle{root: n.root}, fuse.FOPEN_DIRECT_IO, 0
+}
+
+var _ fs.NodeGetattrer = (*controlTxnNode)(nil)
+var _ fs.NodeOpener = (*controlTxnNode)(nil)
+
+// controlTxnHandle implements Read/Write for one open of .redisfs/txn.
+type controlTxnHandle struct {
+	root *FSRoot
+}
+
+// Read reports the active transaction's status.
+func (h *controlTxnHandle) Read(ctx context.Context, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	data := []byte(h.root.txnStatus())
+	if off >= int64(len(data)) {
+		return fuse.ReadResultData(nil), 0
+	}
+	end := off + int64(len(dest))
+	if end > int64(len(data)) {
+		end = int64(len(data))
+	}
+	return fuse.ReadResultData(data[off:end]), 0
+}
+
+// Write interprets the written bytes as a "begin", "commit", or "abort"
+// command for the mount's transaction.
+func (h *controlTxnHandle) Write(ctx context.Context, data []byte, off int64) (uint32, syscall.Errno) {
+	cmd := strings.TrimSpace(strings.ToLower(string(data)))
+
+	var err error
+	switch cmd {
+	case "begin":
+		err = h.root.BeginTxn(ctx)
+	case "commit":
+		err = h.root.CommitTxn(ctx)
+	case "abort":
+		err = h.root.AbortTxn(ctx)
+	default:
+		return 0, syscall.EINVAL
+	}
+	if err != nil {
+		return 0, mapError(err)
+	}
+	return uint32(len(data)), 0
+}
+
+var _ fs.FileReader = (*controlTxnHandle)(nil)
+var _ fs.FileWriter = (*controlTxnHandle)(nil)
@@ -7,10 +7,22 @@ import (
 	"github.com/hanwen/go-fuse/v2/fs"
 	"github.com/hanwen/go-fuse/v2/fuse"
 	"github.com/redis-fs/mount/internal/client"
+	"golang.org/x/sys/unix"
 )
 
+// renameNoReplace mirrors unix.RENAME_NOREPLACE; go-fuse only exports
+// RENAME_EXCHANGE (fs.RENAME_EXCHANGE), so NOREPLACE is named here instead.
+const renameNoReplace = unix.RENAME_NOREPLACE
+
 // Lookup implements fs.NodeLookuper.
 func (n *FSNode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	defer n.root().metrics.Observe("lookup")()
+
+	if n.fsPath == "/" && name == controlDirName {
+		node := n.NewInode(ctx, &controlDirNode{root: n.root()}, fs.StableAttr{Mode: syscall.S_IFDIR})
+		return node, 0
+	}
+
 	child := n.newChild(name)
 
 	// Check attr cache.
@@ -19,42 +31,69 @@ func (n *FSNode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*
 		out.SetEntryTimeout(n.opts.AttrTimeout)
 		out.SetAttrTimeout(n.opts.AttrTimeout)
 		node := n.NewInode(ctx, child, fs.StableAttr{Mode: out.Attr.Mode & syscall.S_IFMT})
+		n.root().registerInode(child.fsPath, node)
 		return node, 0
 	}
+	if n.attrCache.GetNegative(child.fsPath) {
+		return nil, syscall.ENOENT
+	}
 
-	st, err := n.client.Stat(ctx, child.fsPath)
+	st, err := n.client.Stat(ctx, child.redisPath())
 	if err != nil {
 		return nil, mapError(err)
 	}
 	if st == nil {
+		n.attrCache.SetNegative(child.fsPath)
 		return nil, syscall.ENOENT
 	}
 
 	attr := statToAttr(st, n.opts.UID, n.opts.GID)
 	n.attrCache.Set(child.fsPath, attr)
+	n.cacheXattrs(child.fsPath, st.Xattrs)
 
 	out.Attr = attr
 	out.SetEntryTimeout(n.opts.AttrTimeout)
 	out.SetAttrTimeout(n.opts.AttrTimeout)
 
 	node := n.NewInode(ctx, child, fs.StableAttr{Mode: attr.Mode & syscall.S_IFMT})
+	n.root().registerInode(child.fsPath, node)
 	return node, 0
 }
 
 // Readdir implements fs.NodeReaddirer.
 func (n *FSNode) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
+	defer n.root().metrics.Observe("readdir")()
+
 	// Check dir cache.
 	if cached, ok := n.dirCache.Get(n.fsPath); ok {
 		return fs.NewListDirStream(cached.([]fuse.DirEntry)), 0
 	}
 
-	entries, err := n.client.LsLong(ctx, n.fsPath)
+	entries, err := n.client.LsLong(ctx, n.redisPath())
 	if err != nil {
 		return nil, mapError(err)
 	}
 
+	// When the kernel negotiated READDIRPLUS, go-fuse follows this Readdir
+	// with a Lookup per entry to fill in the dentry+attr cache in the same
+	// round trip. Pre-populating attrCache here from the LsLong response
+	// (which already has full stat info) lets those Lookups return from
+	// cache instead of issuing a redundant FS.STAT each.
+	plus := n.root().readDirPlusActive()
+
+	cryptor := n.root().cryptor
 	result := make([]fuse.DirEntry, 0, len(entries))
 	for _, e := range entries {
+		name := e.Name
+		if cryptor != nil {
+			decoded, err := cryptor.DecryptName(name)
+			if err != nil {
+				n.opts.Logger.Printf("readdir: skipping undecryptable entry %q in %s: %v", name, n.fsPath, err)
+				continue
+			}
+			name = decoded
+		}
+
 		var mode uint32
 		switch e.Type {
 		case "file":
@@ -65,14 +104,16 @@ func (n *FSNode) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
 			mode = syscall.S_IFLNK
 		}
 		result = append(result, fuse.DirEntry{
-			Name: e.Name,
+			Name: name,
 			Mode: mode,
 		})
 
-		// Pre-populate attr cache from the long listing.
-		childPath := n.fsPath + "/" + e.Name
+		if !plus {
+			continue
+		}
+		childPath := n.fsPath + "/" + name
 		if n.fsPath == "/" {
-			childPath = "/" + e.Name
+			childPath = "/" + name
 		}
 		n.attrCache.Set(childPath, lsEntryToAttr(&e, n.opts.UID, n.opts.GID))
 	}
@@ -104,36 +145,38 @@ func lsEntryToAttr(e *client.LsEntry, uid, gid uint32) fuse.Attr {
 	}
 
 	return fuse.Attr{
-		Mode:  mode,
-		Nlink: nlink,
-		Size:  size,
-		Owner: fuse.Owner{Uid: uid, Gid: gid},
-		Mtime: uint64(e.Mtime / 1000),
+		Mode:      mode,
+		Nlink:     nlink,
+		Size:      size,
+		Owner:     fuse.Owner{Uid: uid, Gid: gid},
+		Mtime:     uint64(e.Mtime / 1000),
 		Mtimensec: uint32((e.Mtime % 1000) * 1_000_000),
-		Blocks: (size + 511) / 512,
+		Blocks:    (size + 511) / 512,
 	}
 }
 
 // Mkdir implements fs.NodeMkdirer.
 func (n *FSNode) Mkdir(ctx context.Context, name string, mode uint32, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	defer n.root().metrics.Observe("mkdir")()
+
 	if n.opts.ReadOnly {
 		return nil, syscall.EROFS
 	}
 
 	child := n.newChild(name)
 
-	if err := n.client.Mkdir(ctx, child.fsPath); err != nil {
+	if err := n.client.Mkdir(ctx, child.redisPath()); err != nil {
 		return nil, mapError(err)
 	}
 
 	if mode != 0 {
-		_ = n.client.Chmod(ctx, child.fsPath, mode&07777)
+		_ = n.client.Chmod(ctx, child.redisPath(), mode&07777)
 	}
 
 	n.root().invalidatePath(child.fsPath)
 
 	// Fetch the attr for the new dir.
-	st, err := n.client.Stat(ctx, child.fsPath)
+	st, err := n.client.Stat(ctx, child.redisPath())
 	if err != nil {
 		return nil, mapError(err)
 	}
@@ -143,18 +186,21 @@ func (n *FSNode) Mkdir(ctx context.Context, name string, mode uint32, out *fuse.
 	out.SetAttrTimeout(n.opts.AttrTimeout)
 
 	node := n.NewInode(ctx, child, fs.StableAttr{Mode: syscall.S_IFDIR})
+	n.root().registerInode(child.fsPath, node)
 	return node, 0
 }
 
 // Rmdir implements fs.NodeRmdirer.
 func (n *FSNode) Rmdir(ctx context.Context, name string) syscall.Errno {
+	defer n.root().metrics.Observe("rmdir")()
+
 	if n.opts.ReadOnly {
 		return syscall.EROFS
 	}
 
 	child := n.newChild(name)
 
-	if err := n.client.Rm(ctx, child.fsPath); err != nil {
+	if err := n.client.Rm(ctx, child.redisPath()); err != nil {
 		return mapError(err)
 	}
 
@@ -164,16 +210,38 @@ func (n *FSNode) Rmdir(ctx context.Context, name string) syscall.Errno {
 
 // Rename implements fs.NodeRenamer.
 func (n *FSNode) Rename(ctx context.Context, name string, newParent fs.InodeEmbedder, newName string, flags uint32) syscall.Errno {
+	defer n.root().metrics.Observe("rename")()
+
 	if n.opts.ReadOnly {
 		return syscall.EROFS
 	}
 
-	oldPath := n.newChild(name).fsPath
+	oldNode := n.newChild(name)
 	newParentNode := newParent.(*FSNode)
-	newPath := newParentNode.newChild(newName).fsPath
+	newNode := newParentNode.newChild(newName)
+	oldPath, newPath := oldNode.fsPath, newNode.fsPath
 
-	if err := n.client.Mv(ctx, oldPath, newPath); err != nil {
-		return mapError(err)
+	switch {
+	case flags&^(uint32(fs.RENAME_EXCHANGE)|uint32(renameNoReplace)) != 0:
+		return syscall.EINVAL
+
+	case flags&uint32(fs.RENAME_EXCHANGE) != 0:
+		if err := n.client.MvExchange(ctx, oldNode.redisPath(), newNode.redisPath()); err != nil {
+			return mapError(err)
+		}
+		n.root().invalidatePathPrefix(oldPath)
+		n.root().invalidatePathPrefix(newPath)
+		return 0
+
+	case flags&uint32(renameNoReplace) != 0:
+		if err := n.client.MvNoReplace(ctx, oldNode.redisPath(), newNode.redisPath()); err != nil {
+			return mapError(err)
+		}
+
+	default:
+		if err := n.client.Mv(ctx, oldNode.redisPath(), newNode.redisPath()); err != nil {
+			return mapError(err)
+		}
 	}
 
 	n.root().invalidatePathPrefix(oldPath)
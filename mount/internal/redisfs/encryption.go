@@ -0,0 +1,51 @@
+package redisfs
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis-fs/mount/internal/client"
+	"github.com/redis-fs/mount/internal/crypto"
+)
+
+// loadOrCreateCryptoConfig returns the crypto.Config previously stored at
+// c's reserved "<fskey>:cryptoconf" key, or generates and persists a new one
+// (with a fresh random salt) if this is the first encrypted mount of c.
+func loadOrCreateCryptoConfig(ctx context.Context, c *client.Client) (*crypto.Config, error) {
+	data, err := c.ReadCryptoConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("redisfs: reading cryptoconf: %w", err)
+	}
+	if data != nil {
+		return crypto.ParseConfig(data)
+	}
+
+	cfg, err := crypto.GenerateConfig()
+	if err != nil {
+		return nil, fmt.Errorf("redisfs: generating cryptoconf: %w", err)
+	}
+	marshaled, err := cfg.Marshal()
+	if err != nil {
+		return nil, fmt.Errorf("redisfs: marshaling cryptoconf: %w", err)
+	}
+
+	// Two mounts can race to initialize encryption for the same key for the
+	// first time; WriteCryptoConfigIfAbsent makes sure only one of their
+	// randomly-salted Configs actually sticks, so the loser re-reads and uses
+	// that one instead of silently persisting keys nobody else agrees on.
+	stored, err := c.WriteCryptoConfigIfAbsent(ctx, marshaled)
+	if err != nil {
+		return nil, fmt.Errorf("redisfs: writing cryptoconf: %w", err)
+	}
+	if !stored {
+		data, err = c.ReadCryptoConfig(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("redisfs: reading cryptoconf after losing init race: %w", err)
+		}
+		if data == nil {
+			return nil, fmt.Errorf("redisfs: cryptoconf vanished after losing init race")
+		}
+		return crypto.ParseConfig(data)
+	}
+	return cfg, nil
+}
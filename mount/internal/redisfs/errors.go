@@ -1,6 +1,8 @@
 package redisfs
 
 import (
+	"context"
+	"errors"
 	"strings"
 	"syscall"
 )
@@ -10,6 +12,14 @@ func mapError(err error) syscall.Errno {
 	if err == nil {
 		return 0
 	}
+
+	// A canceled or timed-out ctx (e.g. the kernel interrupted the FUSE
+	// request) should look like an interrupted syscall, not a generic I/O
+	// error.
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return syscall.EINTR
+	}
+
 	msg := err.Error()
 
 	switch {
@@ -25,6 +35,10 @@ func mapError(err error) syscall.Errno {
 		return syscall.ENOTDIR
 	case strings.Contains(msg, "already exists"):
 		return syscall.EEXIST
+	case strings.Contains(msg, "no such attribute"):
+		return syscall.ENODATA
+	case strings.Contains(msg, "cross-shard"):
+		return syscall.EXDEV
 	case strings.Contains(msg, "directory not empty"):
 		return syscall.ENOTEMPTY
 	case strings.Contains(msg, "too many levels of symbolic links"):
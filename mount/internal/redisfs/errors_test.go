@@ -1,7 +1,9 @@
 package redisfs
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"syscall"
 	"testing"
 )
@@ -30,3 +32,12 @@ func TestMapError(t *testing.T) {
 		}
 	}
 }
+
+func TestMapErrorContextCancellation(t *testing.T) {
+	if got := mapError(context.Canceled); got != syscall.EINTR {
+		t.Fatalf("mapError(context.Canceled) = %d, want EINTR", got)
+	}
+	if got := mapError(fmt.Errorf("cat: %w", context.DeadlineExceeded)); got != syscall.EINTR {
+		t.Fatalf("mapError(wrapped DeadlineExceeded) = %d, want EINTR", got)
+	}
+}
@@ -2,31 +2,35 @@ package redisfs
 
 import (
 	"context"
+	"strings"
 	"syscall"
 
 	"github.com/hanwen/go-fuse/v2/fs"
 	"github.com/hanwen/go-fuse/v2/fuse"
+	"golang.org/x/sys/unix"
 )
 
 // Create implements fs.NodeCreater.
 func (n *FSNode) Create(ctx context.Context, name string, flags uint32, mode uint32, out *fuse.EntryOut) (inode *fs.Inode, fh fs.FileHandle, fuseFlags uint32, errno syscall.Errno) {
+	defer n.root().metrics.Observe("create")()
+
 	if n.opts.ReadOnly {
 		return nil, nil, 0, syscall.EROFS
 	}
 
 	child := n.newChild(name)
 
-	if err := n.client.Touch(ctx, child.fsPath); err != nil {
+	if err := n.client.Touch(ctx, child.redisPath()); err != nil {
 		return nil, nil, 0, mapError(err)
 	}
 
 	if mode != 0 {
-		_ = n.client.Chmod(ctx, child.fsPath, mode&07777)
+		_ = n.client.Chmod(ctx, child.redisPath(), mode&07777)
 	}
 
 	n.root().invalidatePath(child.fsPath)
 
-	st, err := n.client.Stat(ctx, child.fsPath)
+	st, err := n.client.Stat(ctx, child.redisPath())
 	if err != nil {
 		return nil, nil, 0, mapError(err)
 	}
@@ -36,6 +40,7 @@ func (n *FSNode) Create(ctx context.Context, name string, flags uint32, mode uin
 	out.SetAttrTimeout(n.opts.AttrTimeout)
 
 	node := n.NewInode(ctx, child, fs.StableAttr{Mode: syscall.S_IFREG})
+	n.root().registerInode(child.fsPath, node)
 
 	handle := newFileHandle(child.fsPath, n.client, child)
 	if flags&syscall.O_TRUNC != 0 {
@@ -47,6 +52,8 @@ func (n *FSNode) Create(ctx context.Context, name string, flags uint32, mode uin
 
 // Open implements fs.NodeOpener.
 func (n *FSNode) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
+	defer n.root().metrics.Observe("open")()
+
 	if n.opts.ReadOnly && (flags&(syscall.O_WRONLY|syscall.O_RDWR)) != 0 {
 		return nil, 0, syscall.EROFS
 	}
@@ -62,12 +69,14 @@ func (n *FSNode) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32,
 
 // Read implements fs.NodeReader.
 func (n *FSNode) Read(ctx context.Context, fh fs.FileHandle, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	defer n.root().metrics.Observe("read")()
+
 	if h, ok := fh.(*FileHandle); ok {
 		return h.Read(ctx, dest, off)
 	}
 
 	// Fallback: direct read without handle.
-	data, err := n.client.Cat(ctx, n.fsPath)
+	data, err := n.client.Cat(ctx, n.redisPath())
 	if err != nil {
 		return nil, mapError(err)
 	}
@@ -85,6 +94,8 @@ func (n *FSNode) Read(ctx context.Context, fh fs.FileHandle, dest []byte, off in
 
 // Write implements fs.NodeWriter.
 func (n *FSNode) Write(ctx context.Context, fh fs.FileHandle, data []byte, off int64) (uint32, syscall.Errno) {
+	defer n.root().metrics.Observe("write")()
+
 	if n.opts.ReadOnly {
 		return 0, syscall.EROFS
 	}
@@ -115,19 +126,23 @@ func (n *FSNode) Flush(ctx context.Context, fh fs.FileHandle) syscall.Errno {
 // Release implements fs.NodeReleaser.
 func (n *FSNode) Release(ctx context.Context, fh fs.FileHandle) syscall.Errno {
 	if h, ok := fh.(*FileHandle); ok {
-		return h.Flush(ctx)
+		errno := h.Flush(ctx)
+		n.root().unregisterHandle(h)
+		return errno
 	}
 	return 0
 }
 
 // Unlink implements fs.NodeUnlinker.
 func (n *FSNode) Unlink(ctx context.Context, name string) syscall.Errno {
+	defer n.root().metrics.Observe("unlink")()
+
 	if n.opts.ReadOnly {
 		return syscall.EROFS
 	}
 
 	child := n.newChild(name)
-	if err := n.client.Rm(ctx, child.fsPath); err != nil {
+	if err := n.client.Rm(ctx, child.redisPath()); err != nil {
 		return mapError(err)
 	}
 
@@ -140,19 +155,152 @@ func (n *FSNode) Link(ctx context.Context, target fs.InodeEmbedder, name string,
 	return nil, syscall.ENOTSUP
 }
 
-// Getxattr implements fs.NodeGetxattrer — returns ENODATA (no xattr support).
+// xattrRestricted reports whether attr is in a namespace only root may read
+// or write, mirroring Linux's xattr(7): security.* and trusted.* are
+// privileged, user.* (and anything else) is not.
+func xattrRestricted(attr string) bool {
+	return strings.HasPrefix(attr, "security.") || strings.HasPrefix(attr, "trusted.")
+}
+
+// checkXattrAccess returns EPERM if attr is in a root-only namespace and the
+// calling process isn't root.
+func checkXattrAccess(ctx context.Context, attr string) syscall.Errno {
+	if !xattrRestricted(attr) {
+		return 0
+	}
+	caller, ok := fuse.FromContext(ctx)
+	if !ok || caller.Uid != 0 {
+		return syscall.EPERM
+	}
+	return 0
+}
+
+// Getxattr implements fs.NodeGetxattrer, copying the attribute value into
+// dest and reporting ERANGE if dest is too small to hold it.
 func (n *FSNode) Getxattr(ctx context.Context, attr string, dest []byte) (uint32, syscall.Errno) {
-	return 0, syscall.ENODATA
+	defer n.root().metrics.Observe("getxattr")()
+
+	if n.opts.DisableXattr {
+		return 0, syscall.ENOTSUP
+	}
+	if errno := checkXattrAccess(ctx, attr); errno != 0 {
+		return 0, errno
+	}
+
+	key := xattrKey(n.fsPath, attr)
+	value, ok := n.xattrCache.Get(key)
+	if !ok {
+		v, err := n.client.GetXattr(ctx, n.redisPath(), attr)
+		if err != nil {
+			return 0, mapError(err)
+		}
+		if v == nil {
+			return 0, syscall.ENODATA
+		}
+		n.xattrCache.Set(key, v)
+		value = v
+	}
+
+	data := value.([]byte)
+	if len(dest) < len(data) {
+		return uint32(len(data)), syscall.ERANGE
+	}
+	copy(dest, data)
+	return uint32(len(data)), 0
 }
 
-// Setxattr implements fs.NodeSetxattrer — returns ENOTSUP.
+// Setxattr implements fs.NodeSetxattrer, honoring XATTR_CREATE/XATTR_REPLACE
+// semantics (fail instead of silently overwriting or creating).
 func (n *FSNode) Setxattr(ctx context.Context, attr string, data []byte, flags uint32) syscall.Errno {
-	return syscall.ENOTSUP
+	defer n.root().metrics.Observe("setxattr")()
+
+	if n.opts.DisableXattr {
+		return syscall.ENOTSUP
+	}
+	if n.opts.ReadOnly {
+		return syscall.EROFS
+	}
+	if errno := checkXattrAccess(ctx, attr); errno != 0 {
+		return errno
+	}
+
+	if flags&(unix.XATTR_CREATE|unix.XATTR_REPLACE) != 0 {
+		existing, err := n.client.GetXattr(ctx, n.redisPath(), attr)
+		if err != nil {
+			return mapError(err)
+		}
+		switch {
+		case flags&unix.XATTR_CREATE != 0 && existing != nil:
+			return syscall.EEXIST
+		case flags&unix.XATTR_REPLACE != 0 && existing == nil:
+			return syscall.ENODATA
+		}
+	}
+
+	if err := n.client.SetXattr(ctx, n.redisPath(), attr, data); err != nil {
+		return mapError(err)
+	}
+	n.xattrCache.InvalidatePrefix(xattrNamespace(n.fsPath))
+	return 0
 }
 
-// Listxattr implements fs.NodeListxattrer — returns empty.
+// Listxattr implements fs.NodeListxattrer, copying the NUL-separated
+// attribute names into dest and reporting ERANGE if dest is too small.
+// security.*/trusted.* names are omitted for non-root callers.
 func (n *FSNode) Listxattr(ctx context.Context, dest []byte) (uint32, syscall.Errno) {
-	return 0, 0
+	defer n.root().metrics.Observe("listxattr")()
+
+	if n.opts.DisableXattr {
+		return 0, syscall.ENOTSUP
+	}
+
+	key := xattrKey(n.fsPath, "")
+	var names []string
+	if cached, ok := n.xattrCache.Get(key); ok {
+		names = cached.([]string)
+	} else {
+		ns, err := n.client.ListXattr(ctx, n.redisPath())
+		if err != nil {
+			return 0, mapError(err)
+		}
+		n.xattrCache.Set(key, ns)
+		names = ns
+	}
+
+	var buf []byte
+	for _, name := range names {
+		if checkXattrAccess(ctx, name) != 0 {
+			continue
+		}
+		buf = append(buf, name...)
+		buf = append(buf, 0)
+	}
+	if len(dest) < len(buf) {
+		return uint32(len(buf)), syscall.ERANGE
+	}
+	copy(dest, buf)
+	return uint32(len(buf)), 0
+}
+
+// Removexattr implements fs.NodeRemovexattrer.
+func (n *FSNode) Removexattr(ctx context.Context, attr string) syscall.Errno {
+	defer n.root().metrics.Observe("removexattr")()
+
+	if n.opts.DisableXattr {
+		return syscall.ENOTSUP
+	}
+	if n.opts.ReadOnly {
+		return syscall.EROFS
+	}
+	if errno := checkXattrAccess(ctx, attr); errno != 0 {
+		return errno
+	}
+
+	if err := n.client.RemoveXattr(ctx, n.redisPath(), attr); err != nil {
+		return mapError(err)
+	}
+	n.xattrCache.InvalidatePrefix(xattrNamespace(n.fsPath))
+	return 0
 }
 
 // Ensure interfaces are satisfied.
@@ -168,3 +316,4 @@ var _ fs.NodeLinker = (*FSNode)(nil)
 var _ fs.NodeGetxattrer = (*FSNode)(nil)
 var _ fs.NodeSetxattrer = (*FSNode)(nil)
 var _ fs.NodeListxattrer = (*FSNode)(nil)
+var _ fs.NodeRemovexattrer = (*FSNode)(nil)
@@ -3,10 +3,13 @@ package redisfs
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -14,31 +17,308 @@ import (
 	"github.com/hanwen/go-fuse/v2/fuse"
 	"github.com/redis-fs/mount/internal/cache"
 	"github.com/redis-fs/mount/internal/client"
+	"github.com/redis-fs/mount/internal/crypto"
+	"github.com/redis-fs/mount/internal/metrics"
 )
 
 // Options configures the FUSE mount.
 type Options struct {
 	AttrTimeout time.Duration
 	ReadOnly    bool
+	AllowOther  bool
 	Debug       bool
 	UID         uint32
 	GID         uint32
+
+	// SubscribeInvalidations, when true, subscribes to InvalidationChannel
+	// for cross-mount cache coherence: other mounts of the same Redis FS
+	// key publish invalidated paths there after a write.
+	SubscribeInvalidations bool
+	// InvalidationChannel overrides the pub/sub channel name. Defaults to
+	// client.DefaultInvalidationChannel.
+	InvalidationChannel string
+	// InvalidationPollInterval, when > 0, periodically does a wholesale
+	// cache resync on this interval, for Redis servers that can't publish to
+	// InvalidationChannel (no keyspace-notifications, no FS.WATCH support).
+	// It's coarser and slower than SubscribeInvalidations (other mounts'
+	// writes are only picked up once per interval, not within milliseconds)
+	// but needs nothing from the server beyond what FS.* already requires.
+	// The two aren't mutually exclusive: a poll interval alongside
+	// SubscribeInvalidations bounds the staleness window if the
+	// subscription itself silently stalls.
+	InvalidationPollInterval time.Duration
+
+	// EnableMetrics, when true, registers Prometheus collectors for cache
+	// hits/misses/size, Redis command RTT, FUSE op latency, and
+	// invalidation counts, reachable via FSRoot.MetricsHandler.
+	EnableMetrics bool
+
+	// DisableXattr, when true, makes Getxattr/Setxattr/Listxattr/Removexattr
+	// return ENOTSUP unconditionally instead of reading/writing the
+	// Redis-backed xattr store.
+	DisableXattr bool
+
+	// DisableReadDirPlus turns off the attr-cache pre-population Readdir
+	// does when the kernel negotiated READDIRPLUS (CAP_READDIRPLUS),
+	// falling back to a bare name+mode listing resolved by a Lookup
+	// round-trip per entry. READDIRPLUS pre-population is used by default
+	// whenever the kernel advertises support for it.
+	DisableReadDirPlus bool
+
+	// MaxCacheEntries bounds the attr/dir/xattr caches; the least-recently-
+	// used entry is evicted once it's exceeded. Defaults to
+	// cache.DefaultMaxEntries if <= 0.
+	MaxCacheEntries int
+	// NegativeCacheTimeout is how long a negative Lookup/Getattr (path
+	// confirmed not to exist) is cached before being re-checked against
+	// Redis. Defaults to AttrTimeout if zero.
+	NegativeCacheTimeout time.Duration
+
+	// Logger receives diagnostic messages (cache invalidation failures,
+	// Statfs errors, etc.) in place of the standard logger, and is also
+	// handed to go-fuse's fs.Options.Logger. Defaults to log.Default().
+	Logger *log.Logger
+
+	// PageCacheTTL bounds how long a shared page cache entry (see
+	// FSRoot.pageCache) stays valid before being re-fetched, independent of
+	// the generation bump invalidatePath/Setattr do on a write. Defaults to
+	// AttrTimeout if zero.
+	PageCacheTTL time.Duration
+	// ReadAheadWorkers sizes the background pool that prefetches the next
+	// chunk of a file once FileHandle detects sequential access. <= 0
+	// disables read-ahead.
+	ReadAheadWorkers int
+	// WritebackInterval, when > 0, runs a background goroutine that flushes
+	// every open FileHandle's dirty chunks on this interval, so a long-lived
+	// handle's writes reach Redis without waiting for an explicit
+	// flush/fsync/close. <= 0 disables background writeback.
+	WritebackInterval time.Duration
+
+	// EncryptionPassphrase, when non-nil, turns on gocryptfs-style
+	// encryption at rest: filenames and file content stored in Redis are
+	// ciphertext, decrypted only in this process. The key is derived from
+	// the passphrase with scrypt; the derivation parameters (not the
+	// passphrase or any key material) are persisted at the reserved key
+	// "<fskey>:cryptoconf" so later mounts with the same passphrase derive
+	// the same key. Unset (the default) leaves the mount unencrypted.
+	EncryptionPassphrase []byte
 }
 
 // FSRoot is the root of the FUSE filesystem.
 type FSRoot struct {
 	FSNode
+
+	inodesMu sync.Mutex
+	inodes   map[string]kernelNotifiable // live kernel-visible inodes, keyed by fsPath
+
+	metrics *metrics.Metrics // nil unless Options.EnableMetrics
+	server  *fuse.Server     // set once Mount negotiates with the kernel
+
+	rangeOnce sync.Once
+	rangeOK   bool // whether FS.INFO advertised range_support; valid once rangeOnce fires
+
+	pageCache *cache.Cache // shared (path, generation, chunk idx) -> []byte, across all FileHandles
+
+	genMu      sync.Mutex
+	generation map[string]uint64 // per-path generation, bumped on every write so stale pageCache entries are orphaned
+
+	readAheadCh chan readAheadJob // buffered; a full queue just drops the prefetch
+
+	handlesMu sync.Mutex
+	handles   map[*FileHandle]struct{} // open handles, for the background writeback loop
+
+	cryptor *crypto.Cryptor // nil unless Options.EncryptionPassphrase was set
+}
+
+// redisPath translates a cleartext mount path into the form passed to
+// fsClient calls: unchanged for an unencrypted mount, or name-by-name
+// AES-SIV ciphertext if the mount is encrypted.
+func (r *FSRoot) redisPath(path string) string {
+	if r.cryptor == nil {
+		return path
+	}
+	return r.cryptor.EncryptPath(path)
+}
+
+// readDirPlusActive reports whether Readdir should pre-populate the attr
+// cache for every child, i.e. Options.DisableReadDirPlus is unset and the
+// kernel has advertised CAP_READDIRPLUS support. Before the mount handshake
+// completes (e.g. in unit tests that never call Mount), it reports true.
+func (r *FSRoot) readDirPlusActive() bool {
+	if r.opts.DisableReadDirPlus {
+		return false
+	}
+	if r.server == nil {
+		return true
+	}
+	settings := r.server.KernelSettings()
+	return settings != nil && settings.Flags&fuse.CAP_READDIRPLUS != 0
+}
+
+// rangeSupported reports whether the server advertises FS.WRITE support via
+// FS.INFO, checked once (lazily, on first open file handle) and cached for
+// the life of the mount. FileHandle falls back to whole-file FS.CAT/FS.ECHO
+// buffering when this is false.
+func (r *FSRoot) rangeSupported(ctx context.Context) bool {
+	r.rangeOnce.Do(func() {
+		info, err := r.client.Info(ctx)
+		r.rangeOK = err == nil && info.RangeSupport
+	})
+	return r.rangeOK
+}
+
+// BeginTxn starts a whole-mount transaction via the underlying Client: every
+// mutation issued by any FSNode until CommitTxn/AbortTxn is staged instead
+// of applied to the live tree. Every open FileHandle is flushed first, so a
+// write buffered before this call is reliably untagged (client.doTxn tags a
+// command with whichever txnID is active when the RPC actually fires, not
+// when the FUSE write() call happened, so without this flush a pre-existing
+// buffered write could still be sent by writebackLoop/Release/Flush after
+// the transaction starts and land staged when it shouldn't). This doesn't
+// close every race against a concurrent FUSE write landing mid-transition;
+// it only guarantees handles open at the moment BeginTxn is called.
+func (r *FSRoot) BeginTxn(ctx context.Context) error {
+	if err := r.flushOpenHandles(ctx); err != nil {
+		return err
+	}
+	_, err := r.client.BeginTxn(ctx)
+	return err
+}
+
+// CommitTxn atomically swaps every staged write into the live tree and
+// drops every cache, since any path under the mount may have changed. Every
+// open FileHandle is flushed first, so a write made during the transaction
+// but not yet flushed (by the time this is called) is forced to cross the
+// wire, tagged with the still-active txnID, before Commit -- instead of
+// escaping staging entirely by reaching Redis only after the transaction has
+// already closed. As with BeginTxn, this only covers handles open at the
+// moment CommitTxn is called, not a write racing the Commit call itself.
+func (r *FSRoot) CommitTxn(ctx context.Context) error {
+	if err := r.flushOpenHandles(ctx); err != nil {
+		return err
+	}
+	if err := r.client.CommitTxn(ctx); err != nil {
+		return err
+	}
+	r.invalidateEverything()
+	return nil
+}
+
+// AbortTxn discards every staged write and drops every cache, since reads
+// that happened during the transaction may have observed staged state.
+// Every open FileHandle is flushed first, for the same reason CommitTxn
+// does: a write made during the transaction must be tagged with the
+// transaction being aborted, not left to land untagged on the live tree
+// afterward. Unlike BeginTxn/CommitTxn, a flush failure here is only logged,
+// not returned: abort is the only way to unstick a transaction, so it must
+// go through even if a handle can't be flushed (that handle's dirty data is
+// simply discarded along with everything else the transaction staged).
+func (r *FSRoot) AbortTxn(ctx context.Context) error {
+	if err := r.flushOpenHandles(ctx); err != nil {
+		r.opts.Logger.Printf("flush before abort: %v (aborting anyway)", err)
+	}
+	if err := r.client.AbortTxn(ctx); err != nil {
+		return err
+	}
+	r.invalidateEverything()
+	return nil
+}
+
+// flushOpenHandles flushes every currently open FileHandle's dirty buffer
+// concurrently, so no write issued before this call can reach Redis after it
+// returns. BeginTxn/CommitTxn/AbortTxn all call this immediately before the
+// matching client call, since client.doTxn tags each command with whichever
+// txnID is active when the RPC actually fires, not when the FUSE write()
+// call happened. Returns the first flush error, if any, but still waits for
+// every handle to finish flushing.
+func (r *FSRoot) flushOpenHandles(ctx context.Context) error {
+	handles := r.openHandles()
+
+	var firstErr error
+	var errOnce sync.Once
+	recordErr := func(e error) {
+		if e == nil {
+			return
+		}
+		errOnce.Do(func() { firstErr = e })
+	}
+
+	var wg sync.WaitGroup
+	for _, fh := range handles {
+		wg.Add(1)
+		go func(fh *FileHandle) {
+			defer wg.Done()
+			if errno := fh.Flush(ctx); errno != 0 {
+				recordErr(fmt.Errorf("flush %s: %w", fh.path, errno))
+			}
+		}(fh)
+	}
+	wg.Wait()
+
+	return firstErr
+}
+
+// invalidateEverything drops every cache and pushes kernel invalidations for
+// every currently kernel-visible inode. Used after a transaction commits or
+// aborts, since any path under the mount may have changed.
+func (r *FSRoot) invalidateEverything() {
+	r.inodesMu.Lock()
+	paths := make([]string, 0, len(r.inodes))
+	for path := range r.inodes {
+		paths = append(paths, path)
+	}
+	r.inodesMu.Unlock()
+
+	for _, path := range paths {
+		r.bumpGeneration(path)
+		r.notifyInvalEntry(parentPath(path), baseName(path))
+		r.notifyInvalInode(path)
+	}
+
+	r.attrCache.InvalidateAll()
+	r.dirCache.InvalidateAll()
+	r.xattrCache.InvalidateAll()
+	if r.pageCache != nil {
+		r.pageCache.InvalidateAll()
+	}
+}
+
+// txnStatus reports the active transaction id, or "idle" if none, for the
+// .redisfs/txn control file's Read.
+func (r *FSRoot) txnStatus() string {
+	if id := r.client.ActiveTxn(); id != "" {
+		return "active " + id + "\n"
+	}
+	return "idle\n"
+}
+
+// MetricsHandler returns an http.Handler serving this mount's Prometheus
+// metrics, or nil if Options.EnableMetrics was false.
+func (r *FSRoot) MetricsHandler() http.Handler {
+	if r.metrics == nil {
+		return nil
+	}
+	return r.metrics.Handler()
+}
+
+// kernelNotifiable is the subset of *fs.Inode used to push kernel-level
+// cache invalidations. It exists so tests can fake the kernel notifier
+// without mounting a real FUSE server.
+type kernelNotifiable interface {
+	NotifyEntry(name string) syscall.Errno
+	NotifyContent(off, sz int64) syscall.Errno
 }
 
 // FSNode represents a node (file, directory, or symlink) in the filesystem.
 type FSNode struct {
 	fs.Inode
 
-	client    *client.Client
-	attrCache *cache.Cache
-	dirCache  *cache.Cache
-	opts      *Options
-	fsPath    string // absolute path in the Redis FS (e.g. "/", "/foo/bar")
+	client     fsClient
+	attrCache  *cache.Cache
+	dirCache   *cache.Cache
+	xattrCache *cache.Cache
+	opts       *Options
+	fsPath     string // absolute path in the Redis FS (e.g. "/", "/foo/bar"); always cleartext
 }
 
 // root returns the FSRoot from any node.
@@ -46,14 +326,105 @@ func (n *FSNode) root() *FSRoot {
 	return n.Root().Operations().(*FSRoot)
 }
 
+// redisPath is the path passed to n.client calls: n.fsPath unchanged for an
+// unencrypted mount, or its encrypted form otherwise.
+func (n *FSNode) redisPath() string {
+	return n.root().redisPath(n.fsPath)
+}
+
 // invalidatePath invalidates caches for a path and its parent directory.
 func (r *FSRoot) invalidatePath(path string) {
+	r.bumpGeneration(path)
 	r.attrCache.Invalidate(path)
+	r.xattrCache.InvalidatePrefix(xattrNamespace(path))
 	parent := filepath.Dir(path)
 	r.dirCache.Invalidate(parent)
 	r.attrCache.Invalidate(parent)
 }
 
+// invalidatePathPrefix drops every attrCache/dirCache/xattrCache entry whose
+// key starts with prefix (e.g. after a move or rename touches a whole
+// subtree), and pushes matching FUSE kernel-level invalidations so the VFS
+// dentry and page caches don't keep serving stale lookups/readdirs until
+// AttrTimeout expires.
+func (r *FSRoot) invalidatePathPrefix(prefix string) {
+	r.bumpGeneration(prefix)
+	for _, path := range r.inodesUnderPrefix(prefix) {
+		r.bumpGeneration(path)
+		r.notifyInvalEntry(parentPath(path), baseName(path))
+		r.notifyInvalInode(path)
+	}
+
+	r.attrCache.InvalidatePrefix(prefix)
+	r.dirCache.InvalidatePrefix(prefix)
+	r.xattrCache.InvalidatePrefix(prefix)
+
+	parent := parentPath(prefix)
+	r.dirCache.Invalidate(parent)
+	r.attrCache.Invalidate(parent)
+}
+
+// registerInode records the live kernel-visible inode for path, so later
+// invalidatePathPrefix calls can find it to push kernel notifications.
+func (r *FSRoot) registerInode(path string, node kernelNotifiable) {
+	r.inodesMu.Lock()
+	defer r.inodesMu.Unlock()
+	if r.inodes == nil {
+		r.inodes = make(map[string]kernelNotifiable)
+	}
+	r.inodes[path] = node
+}
+
+// unregisterInode drops the registry entry for path, e.g. once the kernel
+// has forgotten the inode.
+func (r *FSRoot) unregisterInode(path string) {
+	r.inodesMu.Lock()
+	defer r.inodesMu.Unlock()
+	delete(r.inodes, path)
+}
+
+// inodesUnderPrefix returns the registered paths at or below prefix.
+func (r *FSRoot) inodesUnderPrefix(prefix string) []string {
+	r.inodesMu.Lock()
+	defer r.inodesMu.Unlock()
+	var paths []string
+	for path := range r.inodes {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// notifyInvalEntry tells the kernel to drop its dentry cache entry for
+// (parent, name), forcing a fresh LOOKUP on next access. EBUSY/ENOENT are
+// ignored: the kernel may have already evicted the entry on its own.
+func (r *FSRoot) notifyInvalEntry(parent, name string) {
+	r.inodesMu.Lock()
+	parentNode := r.inodes[parent]
+	r.inodesMu.Unlock()
+	if parentNode == nil || name == "" {
+		return
+	}
+	if errno := parentNode.NotifyEntry(name); errno != 0 && errno != syscall.EBUSY && errno != syscall.ENOENT {
+		r.opts.Logger.Printf("NotifyEntry(%s, %s) failed: %v", parent, name, errno)
+	}
+}
+
+// notifyInvalInode tells the kernel to drop the attr/data cache for path's
+// inode over the full range, ignoring the usual EBUSY/ENOENT races.
+func (r *FSRoot) notifyInvalInode(path string) {
+	r.inodesMu.Lock()
+	node := r.inodes[path]
+	r.inodesMu.Unlock()
+	if node == nil {
+		return
+	}
+	if errno := node.NotifyContent(0, -1); errno != 0 && errno != syscall.EBUSY && errno != syscall.ENOENT {
+		r.opts.Logger.Printf("NotifyContent(%s) failed: %v", path, errno)
+	}
+}
+
 // newChild creates a child FSNode for the given basename.
 func (n *FSNode) newChild(name string) *FSNode {
 	childPath := n.fsPath + "/" + name
@@ -61,45 +432,109 @@ func (n *FSNode) newChild(name string) *FSNode {
 		childPath = "/" + name
 	}
 	return &FSNode{
-		client:    n.client,
-		attrCache: n.attrCache,
-		dirCache:  n.dirCache,
-		opts:      n.opts,
-		fsPath:    childPath,
+		client:     n.client,
+		attrCache:  n.attrCache,
+		dirCache:   n.dirCache,
+		xattrCache: n.xattrCache,
+		opts:       n.opts,
+		fsPath:     childPath,
 	}
 }
 
-// Mount mounts the Redis FS at the given mountpoint.
-func Mount(mountpoint string, c *client.Client, opts *Options) (*fuse.Server, error) {
+// Mount mounts the Redis FS at the given mountpoint. The returned *FSRoot is
+// only useful for its MetricsHandler; most callers can ignore it.
+func Mount(mountpoint string, c *client.Client, opts *Options) (*fuse.Server, *FSRoot, error) {
 	if opts.AttrTimeout == 0 {
 		opts.AttrTimeout = time.Second
 	}
+	if opts.Logger == nil {
+		opts.Logger = log.Default()
+	}
+	if opts.PageCacheTTL == 0 {
+		opts.PageCacheTTL = opts.AttrTimeout
+	}
 
-	attrCache := cache.New(opts.AttrTimeout)
-	dirCache := cache.New(opts.AttrTimeout)
+	var m *metrics.Metrics
+	attrHooks := cache.Hooks{}
+	dirHooks := cache.Hooks{}
+	xattrHooks := cache.Hooks{}
+	if opts.EnableMetrics {
+		m = metrics.New()
+		attrHooks = cacheMetricsHooks(m, "attr")
+		dirHooks = cacheMetricsHooks(m, "dir")
+		xattrHooks = cacheMetricsHooks(m, "xattr")
+		c.SetCommandObserver(func(cmd string, dur time.Duration) {
+			m.RedisCommandDuration.WithLabelValues(cmd).Observe(dur.Seconds())
+		})
+	}
+
+	attrCache := cache.NewWithOptions(cache.Options{
+		TTL:         opts.AttrTimeout,
+		NegativeTTL: opts.NegativeCacheTimeout,
+		MaxEntries:  opts.MaxCacheEntries,
+		Hooks:       attrHooks,
+	})
+	dirCache := cache.NewWithOptions(cache.Options{
+		TTL:        opts.AttrTimeout,
+		MaxEntries: opts.MaxCacheEntries,
+		Hooks:      dirHooks,
+	})
+	xattrCache := cache.NewWithOptions(cache.Options{
+		TTL:        opts.AttrTimeout,
+		MaxEntries: opts.MaxCacheEntries,
+		Hooks:      xattrHooks,
+	})
+	pageCache := cache.NewWithOptions(cache.Options{
+		TTL:        opts.PageCacheTTL,
+		MaxEntries: opts.MaxCacheEntries,
+	})
+
+	var fsc fsClient = c
+	var cryptor *crypto.Cryptor
+	if opts.EncryptionPassphrase != nil {
+		cfg, err := loadOrCreateCryptoConfig(context.Background(), c)
+		if err != nil {
+			return nil, nil, err
+		}
+		keys, err := crypto.DeriveKeys(opts.EncryptionPassphrase, cfg)
+		if err != nil {
+			return nil, nil, fmt.Errorf("redisfs: deriving encryption keys: %w", err)
+		}
+		cryptor, err = crypto.NewCryptor(keys)
+		if err != nil {
+			return nil, nil, fmt.Errorf("redisfs: building cryptor: %w", err)
+		}
+		fsc = crypto.Wrap(c, cryptor)
+	}
 
 	root := &FSRoot{
 		FSNode: FSNode{
-			client:    c,
-			attrCache: attrCache,
-			dirCache:  dirCache,
-			opts:      opts,
-			fsPath:    "/",
+			client:     fsc,
+			attrCache:  attrCache,
+			dirCache:   dirCache,
+			xattrCache: xattrCache,
+			opts:       opts,
+			fsPath:     "/",
 		},
+		metrics:   m,
+		pageCache: pageCache,
+		cryptor:   cryptor,
 	}
 
 	fuseOpts := &fs.Options{
 		MountOptions: fuse.MountOptions{
-			AllowOther: false,
+			AllowOther: opts.AllowOther,
 			FsName:     "redis-fs",
 			Name:       "redis-fs",
 			Debug:      opts.Debug,
+			Logger:     opts.Logger,
 		},
 		EntryTimeout: &opts.AttrTimeout,
 		AttrTimeout:  &opts.AttrTimeout,
 
-		UID: opts.UID,
-		GID: opts.GID,
+		UID:    opts.UID,
+		GID:    opts.GID,
+		Logger: opts.Logger,
 	}
 
 	if opts.ReadOnly {
@@ -108,16 +543,58 @@ func Mount(mountpoint string, c *client.Client, opts *Options) (*fuse.Server, er
 
 	server, err := fs.Mount(mountpoint, root, fuseOpts)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	root.server = server
+	root.registerInode("/", &root.FSNode.Inode)
+	root.startBackgroundWorkers()
+
+	if opts.SubscribeInvalidations {
+		channel := opts.InvalidationChannel
+		if channel == "" {
+			channel = client.DefaultInvalidationChannel
+		}
+		c.SubscribeInvalidations(context.Background(), channel, root.invalidatePathPrefix, root.resync)
+	}
+
+	return server, root, nil
+}
+
+// resync flushes both caches wholesale. It's called after the cross-mount
+// invalidation subscription drops, since messages published while we were
+// disconnected would otherwise leave stale entries behind indefinitely.
+func (r *FSRoot) resync() {
+	r.resyncReason("pubsub")
+}
+
+// resyncReason is resync's implementation, taking the metrics label to
+// record so pollInvalidationLoop's wholesale resyncs are distinguishable
+// from ones triggered by a dropped pub/sub subscription.
+func (r *FSRoot) resyncReason(reason string) {
+	r.attrCache.InvalidateAll()
+	r.dirCache.InvalidateAll()
+	if r.metrics != nil {
+		r.metrics.Invalidations.WithLabelValues(reason).Inc()
+	}
+}
+
+// cacheMetricsHooks wires a cache's Get/Set/size activity into m's
+// collectors under the given cache name ("attr" or "dir").
+func cacheMetricsHooks(m *metrics.Metrics, name string) cache.Hooks {
+	return cache.Hooks{
+		OnHit:        func(string) { m.CacheHits.WithLabelValues(name).Inc() },
+		OnMiss:       func(string) { m.CacheMisses.WithLabelValues(name).Inc() },
+		OnSizeChange: func(size int) { m.CacheSize.WithLabelValues(name).Set(float64(size)) },
 	}
-	return server, nil
 }
 
 // Statfs implements fs.NodeStatfser.
 func (n *FSNode) Statfs(ctx context.Context, out *fuse.StatfsOut) syscall.Errno {
+	defer n.root().metrics.Observe("statfs")()
+
 	info, err := n.client.Info(ctx)
 	if err != nil {
-		log.Printf("Statfs error: %v", err)
+		n.opts.Logger.Printf("Statfs error: %v", err)
 		return syscall.EIO
 	}
 
@@ -140,44 +617,69 @@ func (n *FSNode) Statfs(ctx context.Context, out *fuse.StatfsOut) syscall.Errno
 
 // Getattr implements fs.NodeGetattrer.
 func (n *FSNode) Getattr(ctx context.Context, fh fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	defer n.root().metrics.Observe("getattr")()
+
 	// Check cache first.
 	if cached, ok := n.attrCache.Get(n.fsPath); ok {
 		out.Attr = cached.(fuse.Attr)
 		out.SetTimeout(n.opts.AttrTimeout)
 		return 0
 	}
+	if n.attrCache.GetNegative(n.fsPath) {
+		return syscall.ENOENT
+	}
 
-	st, err := n.client.Stat(ctx, n.fsPath)
+	st, err := n.client.Stat(ctx, n.redisPath())
 	if err != nil {
 		return mapError(err)
 	}
 	if st == nil {
+		n.attrCache.SetNegative(n.fsPath)
 		return syscall.ENOENT
 	}
 
 	attr := statToAttr(st, n.opts.UID, n.opts.GID)
 	n.attrCache.Set(n.fsPath, attr)
+	n.cacheXattrs(n.fsPath, st.Xattrs)
 	out.Attr = attr
 	out.SetTimeout(n.opts.AttrTimeout)
 	return 0
 }
 
+// cacheXattrs prefills the xattr cache for path from a FS.STAT response that
+// included an "xattrs" field, saving a round-trip on a later Listxattr or
+// Getxattr. No-op if xattrs is nil (the server didn't report them).
+func (n *FSNode) cacheXattrs(path string, xattrs map[string][]byte) {
+	if xattrs == nil {
+		return
+	}
+	names := make([]string, 0, len(xattrs))
+	for name, value := range xattrs {
+		n.xattrCache.Set(xattrKey(path, name), value)
+		names = append(names, name)
+	}
+	n.xattrCache.Set(xattrKey(path, ""), names)
+}
+
 // Setattr implements fs.NodeSetattrer.
 func (n *FSNode) Setattr(ctx context.Context, fh fs.FileHandle, in *fuse.SetAttrIn, out *fuse.AttrOut) syscall.Errno {
+	defer n.root().metrics.Observe("setattr")()
+
 	if n.opts.ReadOnly {
 		return syscall.EROFS
 	}
 
 	// Handle truncate.
 	if sz, ok := in.GetSize(); ok {
-		if err := n.client.Truncate(ctx, n.fsPath, int64(sz)); err != nil {
+		if err := n.client.Truncate(ctx, n.redisPath(), int64(sz)); err != nil {
 			return mapError(err)
 		}
+		n.root().bumpGeneration(n.fsPath)
 	}
 
 	// Handle mode change.
 	if mode, ok := in.GetMode(); ok {
-		if err := n.client.Chmod(ctx, n.fsPath, mode&07777); err != nil {
+		if err := n.client.Chmod(ctx, n.redisPath(), mode&07777); err != nil {
 			return mapError(err)
 		}
 	}
@@ -194,7 +696,7 @@ func (n *FSNode) Setattr(ctx context.Context, fh fs.FileHandle, in *fuse.SetAttr
 		if gidOk {
 			newGID = gid
 		}
-		if err := n.client.Chown(ctx, n.fsPath, newUID, newGID); err != nil {
+		if err := n.client.Chown(ctx, n.redisPath(), newUID, newGID); err != nil {
 			return mapError(err)
 		}
 	}
@@ -211,9 +713,10 @@ func (n *FSNode) Setattr(ctx context.Context, fh fs.FileHandle, in *fuse.SetAttr
 		if mtimeOk {
 			mtimeMs = mtime.UnixNano() / 1_000_000
 		}
-		if err := n.client.Utimens(ctx, n.fsPath, atimeMs, mtimeMs); err != nil {
+		if err := n.client.Utimens(ctx, n.redisPath(), atimeMs, mtimeMs); err != nil {
 			return mapError(err)
 		}
+		n.root().bumpGeneration(n.fsPath)
 	}
 
 	n.attrCache.Invalidate(n.fsPath)
@@ -221,6 +724,12 @@ func (n *FSNode) Setattr(ctx context.Context, fh fs.FileHandle, in *fuse.SetAttr
 	return n.Getattr(ctx, fh, out)
 }
 
+// OnForget implements fs.NodeOnForgetter, dropping the node from the kernel
+// inode registry once the kernel has no more references to it.
+func (n *FSNode) OnForget() {
+	n.root().unregisterInode(n.fsPath)
+}
+
 // GetOwnership returns the uid/gid to use. Defaults come from opts.
 func GetOwnership() (uint32, uint32) {
 	return uint32(os.Getuid()), uint32(os.Getgid())
@@ -238,6 +747,18 @@ func parentPath(p string) string {
 	return parent
 }
 
+// xattrNamespace is the xattrCache key prefix covering every attribute
+// cached for path, so a single InvalidatePrefix(xattrNamespace(path)) drops
+// them all.
+func xattrNamespace(path string) string {
+	return path + "\x00"
+}
+
+// xattrKey is the xattrCache key for a single attribute of path.
+func xattrKey(path, name string) string {
+	return xattrNamespace(path) + name
+}
+
 // baseName returns the last component of a path.
 func baseName(p string) string {
 	if p == "/" {
@@ -251,3 +772,4 @@ func baseName(p string) string {
 var _ fs.NodeStatfser = (*FSNode)(nil)
 var _ fs.NodeGetattrer = (*FSNode)(nil)
 var _ fs.NodeSetattrer = (*FSNode)(nil)
+var _ fs.NodeOnForgetter = (*FSNode)(nil)
@@ -1,17 +1,36 @@
 package redisfs
 
 import (
+	"syscall"
 	"testing"
 	"time"
 
 	"github.com/redis-fs/mount/internal/cache"
 )
 
+// fakeNotifiable records NotifyEntry/NotifyContent calls in place of a real
+// *fs.Inode, so kernel-invalidation behavior can be tested without mounting.
+type fakeNotifiable struct {
+	entryCalls   []string
+	contentCalls int
+}
+
+func (f *fakeNotifiable) NotifyEntry(name string) syscall.Errno {
+	f.entryCalls = append(f.entryCalls, name)
+	return 0
+}
+
+func (f *fakeNotifiable) NotifyContent(off, sz int64) syscall.Errno {
+	f.contentCalls++
+	return 0
+}
+
 func TestInvalidatePathPrefix(t *testing.T) {
 	root := &FSRoot{
 		FSNode: FSNode{
-			attrCache: cache.New(time.Minute),
-			dirCache:  cache.New(time.Minute),
+			attrCache:  cache.New(time.Minute),
+			dirCache:   cache.New(time.Minute),
+			xattrCache: cache.New(time.Minute),
 		},
 	}
 
@@ -21,6 +40,9 @@ func TestInvalidatePathPrefix(t *testing.T) {
 	root.dirCache.Set("/a", 1)
 	root.dirCache.Set("/a/b", 2)
 	root.dirCache.Set("/", 3)
+	root.xattrCache.Set(xattrKey("/a", "user.foo"), []byte("v1"))
+	root.xattrCache.Set(xattrKey("/a/b", "user.foo"), []byte("v2"))
+	root.xattrCache.Set(xattrKey("/x", "user.foo"), []byte("v3"))
 
 	root.invalidatePathPrefix("/a")
 
@@ -36,10 +58,56 @@ func TestInvalidatePathPrefix(t *testing.T) {
 	if _, ok := root.dirCache.Get("/a/b"); ok {
 		t.Fatalf("expected /a/b dir cache invalidated")
 	}
+	if _, ok := root.xattrCache.Get(xattrKey("/a", "user.foo")); ok {
+		t.Fatalf("expected /a xattr cache invalidated")
+	}
+	if _, ok := root.xattrCache.Get(xattrKey("/a/b", "user.foo")); ok {
+		t.Fatalf("expected /a/b xattr cache invalidated")
+	}
 	if _, ok := root.attrCache.Get("/x"); !ok {
 		t.Fatalf("expected unrelated attr cache entry to remain")
 	}
+	if _, ok := root.xattrCache.Get(xattrKey("/x", "user.foo")); !ok {
+		t.Fatalf("expected unrelated xattr cache entry to remain")
+	}
 	if _, ok := root.dirCache.Get("/"); ok {
 		t.Fatalf("expected parent dir cache (/) invalidated")
 	}
 }
+
+func TestInvalidatePathPrefixNotifiesKernel(t *testing.T) {
+	root := &FSRoot{
+		FSNode: FSNode{
+			attrCache:  cache.New(time.Minute),
+			dirCache:   cache.New(time.Minute),
+			xattrCache: cache.New(time.Minute),
+		},
+	}
+
+	rootNode := &fakeNotifiable{}
+	a := &fakeNotifiable{}
+	ab := &fakeNotifiable{}
+	x := &fakeNotifiable{}
+	root.registerInode("/", rootNode)
+	root.registerInode("/a", a)
+	root.registerInode("/a/b", ab)
+	root.registerInode("/x", x)
+
+	root.invalidatePathPrefix("/a")
+
+	if len(rootNode.entryCalls) != 1 || rootNode.entryCalls[0] != "a" {
+		t.Fatalf("expected NotifyEntry(parent=/, name=a), got %v", rootNode.entryCalls)
+	}
+	if a.contentCalls != 1 {
+		t.Fatalf("expected NotifyContent on /a, got %d calls", a.contentCalls)
+	}
+	if len(a.entryCalls) != 1 || a.entryCalls[0] != "b" {
+		t.Fatalf("expected NotifyEntry(parent=/a, name=b), got %v", a.entryCalls)
+	}
+	if ab.contentCalls != 1 {
+		t.Fatalf("expected NotifyContent on /a/b, got %d calls", ab.contentCalls)
+	}
+	if len(x.entryCalls) != 0 || x.contentCalls != 0 {
+		t.Fatalf("expected unrelated /x inode untouched, got entry=%v content=%d", x.entryCalls, x.contentCalls)
+	}
+}
@@ -1,6 +1,7 @@
 package redisfs
 
 import (
+	"container/list"
 	"context"
 	"sync"
 	"syscall"
@@ -9,23 +10,86 @@ import (
 	"github.com/redis-fs/mount/internal/client"
 )
 
-// FileHandle manages buffered I/O for an open file.
+// chunkSize is the granularity FileHandle fetches/flushes in when the
+// server supports ranged I/O, chosen to keep a single open handle's
+// resident memory bounded regardless of file size.
+const chunkSize = 1 << 20 // 1 MiB
+
+// maxOpenChunks bounds how many chunks a single FileHandle keeps resident
+// before evicting the least-recently-used one (flushing it first if dirty).
+const maxOpenChunks = 64 // ~64 MiB per open handle
+
+// chunkEntry is one chunkSize-aligned slice of a file's content.
+type chunkEntry struct {
+	idx   int64
+	data  []byte
+	dirty bool
+}
+
+// FileHandle manages buffered I/O for an open file. When the server
+// advertises range support (FS.INFO), it serves Read/Write from an LRU of
+// fixed-size chunks fetched via Client.Read and flushed via
+// Client.WriteRange on demand, so opening a multi-GB file doesn't pull it
+// all into RAM. Otherwise it falls back to the original whole-file
+// FS.CAT/FS.ECHO buffering.
 type FileHandle struct {
-	path   string
-	client *client.Client
+	path   string // cleartext; use redisPath() for client calls
+	client fsClient
 	node   *FSNode
 
-	mu      sync.Mutex
-	content []byte // fetched lazily on first Read
-	loaded  bool   // whether content has been fetched
-	dirty   bool   // whether buffer has been modified
+	mu sync.Mutex
+
+	modeDecided bool // selectMode has run
+	chunked     bool // decided on first access, from node.root().rangeSupported
+
+	// Whole-file path (chunked == false).
+	content []byte
+	loaded  bool
+	dirty   bool
+
+	// Chunked path (chunked == true).
+	size        int64 // -1 until known
+	chunks      map[int64]*list.Element
+	lru         *list.List // of *chunkEntry, front = most recently used
+	truncated   bool
+	lastReadEnd int64 // end offset of the previous Read, for sequential-access detection; -1 if none yet
 }
 
-func newFileHandle(path string, c *client.Client, node *FSNode) *FileHandle {
-	return &FileHandle{
-		path:   path,
-		client: c,
-		node:   node,
+func newFileHandle(path string, c fsClient, node *FSNode) *FileHandle {
+	fh := &FileHandle{
+		path:        path,
+		client:      c,
+		node:        node,
+		size:        -1,
+		lastReadEnd: -1,
+	}
+	node.root().registerHandle(fh)
+	return fh
+}
+
+// redisPath is the path passed to fh.client calls: fh.path unchanged for an
+// unencrypted mount, or its encrypted form otherwise.
+func (fh *FileHandle) redisPath() string {
+	return fh.node.root().redisPath(fh.path)
+}
+
+// selectMode decides, on first use, whether this handle uses the chunked
+// path or the whole-file fallback, and initializes the chosen path's state.
+func (fh *FileHandle) selectMode(ctx context.Context) {
+	if fh.modeDecided {
+		return
+	}
+	fh.modeDecided = true
+	if !fh.node.root().rangeSupported(ctx) {
+		return // stays in whole-file mode; load() handles the rest
+	}
+	fh.chunked = true
+	fh.chunks = make(map[int64]*list.Element)
+	fh.lru = list.New()
+	if fh.truncated {
+		// SetTruncated ran before the mode was decided; start the chunk
+		// view at size 0 instead of fetching the (stale) size from Redis.
+		fh.size = 0
 	}
 }
 
@@ -33,7 +97,7 @@ func (fh *FileHandle) load(ctx context.Context) error {
 	if fh.loaded {
 		return nil
 	}
-	data, err := fh.client.Cat(ctx, fh.path)
+	data, err := fh.client.Cat(ctx, fh.redisPath())
 	if err != nil {
 		// File might be empty or new
 		if mapError(err) == syscall.ENOENT {
@@ -53,6 +117,11 @@ func (fh *FileHandle) Read(ctx context.Context, dest []byte, off int64) (fuse.Re
 	fh.mu.Lock()
 	defer fh.mu.Unlock()
 
+	fh.selectMode(ctx)
+	if fh.chunked {
+		return fh.readChunked(ctx, dest, off)
+	}
+
 	if err := fh.load(ctx); err != nil {
 		return nil, mapError(err)
 	}
@@ -75,6 +144,11 @@ func (fh *FileHandle) Write(ctx context.Context, data []byte, off int64) (uint32
 	fh.mu.Lock()
 	defer fh.mu.Unlock()
 
+	fh.selectMode(ctx)
+	if fh.chunked {
+		return fh.writeChunked(ctx, data, off)
+	}
+
 	if err := fh.load(ctx); err != nil {
 		return 0, mapError(err)
 	}
@@ -97,6 +171,10 @@ func (fh *FileHandle) Flush(ctx context.Context) syscall.Errno {
 	fh.mu.Lock()
 	defer fh.mu.Unlock()
 
+	if fh.chunked {
+		return fh.flushChunked(ctx)
+	}
+
 	if !fh.dirty {
 		return 0
 	}
@@ -106,7 +184,7 @@ func (fh *FileHandle) Flush(ctx context.Context) syscall.Errno {
 		data = []byte{}
 	}
 
-	if err := fh.client.Echo(ctx, fh.path, data); err != nil {
+	if err := fh.client.Echo(ctx, fh.redisPath(), data); err != nil {
 		return mapError(err)
 	}
 	fh.dirty = false
@@ -117,11 +195,273 @@ func (fh *FileHandle) Flush(ctx context.Context) syscall.Errno {
 	return 0
 }
 
-// SetTruncated marks the handle as truncated (empty, dirty).
+// SetTruncated marks the handle as truncated (empty, dirty). May be called
+// before the chunked/whole-file mode is decided (e.g. on O_TRUNC open), in
+// which case selectMode picks it up once the mode is known.
 func (fh *FileHandle) SetTruncated() {
 	fh.mu.Lock()
 	defer fh.mu.Unlock()
+
 	fh.content = []byte{}
 	fh.loaded = true
 	fh.dirty = true
+	fh.truncated = true
+
+	if fh.chunked {
+		fh.chunks = make(map[int64]*list.Element)
+		fh.lru = list.New()
+		fh.size = 0
+	}
+}
+
+// fileSize returns the handle's current notion of the file's size,
+// fetching it via Client.Size on first use.
+func (fh *FileHandle) fileSize(ctx context.Context) (int64, error) {
+	if fh.size >= 0 {
+		return fh.size, nil
+	}
+	size, err := fh.client.Size(ctx, fh.redisPath())
+	if err != nil {
+		return 0, err
+	}
+	if size < 0 {
+		size = 0 // new file
+	}
+	fh.size = size
+	return fh.size, nil
+}
+
+// chunkAt returns the chunkEntry covering idx, fetching it from Redis (or
+// creating an empty one past the current end of file) if not resident, and
+// marking it most-recently-used.
+func (fh *FileHandle) chunkAt(ctx context.Context, idx int64) (*chunkEntry, error) {
+	if elem, ok := fh.chunks[idx]; ok {
+		fh.lru.MoveToFront(elem)
+		return elem.Value.(*chunkEntry), nil
+	}
+
+	size, err := fh.fileSize(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	start := idx * chunkSize
+	root := fh.node.root()
+	var data []byte
+	if start < size {
+		key := root.pageCacheKey(fh.path, idx)
+		if cached, ok := root.pageCache.Get(key); ok {
+			data = cached.([]byte)
+		} else {
+			length := int64(chunkSize)
+			if start+length > size {
+				length = size - start
+			}
+			data, err = fh.client.Read(ctx, fh.redisPath(), start, length)
+			if err != nil {
+				return nil, err
+			}
+			root.pageCache.Set(key, data)
+		}
+	}
+
+	buf := make([]byte, chunkSize)
+	copy(buf, data)
+	entry := &chunkEntry{idx: idx, data: buf}
+
+	if err := fh.evictIfFull(ctx); err != nil {
+		return nil, err
+	}
+	fh.chunks[idx] = fh.lru.PushFront(entry)
+	return entry, nil
+}
+
+// evictIfFull flushes and drops the least-recently-used chunk once the
+// handle is at maxOpenChunks, so a sequential scan of a huge file keeps
+// resident memory bounded.
+func (fh *FileHandle) evictIfFull(ctx context.Context) error {
+	if fh.lru.Len() < maxOpenChunks {
+		return nil
+	}
+	back := fh.lru.Back()
+	entry := back.Value.(*chunkEntry)
+	if entry.dirty {
+		if err := fh.flushChunk(ctx, entry); err != nil {
+			return err
+		}
+	}
+	fh.lru.Remove(back)
+	delete(fh.chunks, entry.idx)
+	return nil
+}
+
+// flushChunk writes one dirty chunk back via WriteRange, trimmed to the
+// portion that falls within the current file size.
+func (fh *FileHandle) flushChunk(ctx context.Context, entry *chunkEntry) error {
+	start := entry.idx * chunkSize
+	length := int64(chunkSize)
+	if start+length > fh.size {
+		length = fh.size - start
+	}
+	if length <= 0 {
+		entry.dirty = false
+		return nil
+	}
+	if err := fh.client.WriteRange(ctx, fh.redisPath(), start, entry.data[:length]); err != nil {
+		return err
+	}
+	entry.dirty = false
+	return nil
+}
+
+// readChunked serves a Read from the chunk LRU, fetching only the chunks
+// dest actually overlaps.
+func (fh *FileHandle) readChunked(ctx context.Context, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	size, err := fh.fileSize(ctx)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	if off >= size {
+		return fuse.ReadResultData(nil), 0
+	}
+	end := off + int64(len(dest))
+	if end > size {
+		end = size
+	}
+
+	sequential := off == fh.lastReadEnd
+
+	out := make([]byte, 0, end-off)
+	var lastIdx int64 = -1
+	for pos := off; pos < end; {
+		idx := pos / chunkSize
+		lastIdx = idx
+		entry, err := fh.chunkAt(ctx, idx)
+		if err != nil {
+			return nil, mapError(err)
+		}
+		chunkOff := pos - idx*chunkSize
+		chunkEnd := int64(chunkSize)
+		if idx*chunkSize+chunkEnd > end {
+			chunkEnd = end - idx*chunkSize
+		}
+		out = append(out, entry.data[chunkOff:chunkEnd]...)
+		pos = idx*chunkSize + chunkEnd
+	}
+	fh.lastReadEnd = end
+
+	// A sequential scan (e.g. cp/tar reading a file start to end) is the
+	// pattern read-ahead pays off for; a one-off random read isn't worth
+	// prefetching past.
+	if sequential && lastIdx >= 0 && (lastIdx+1)*chunkSize < size {
+		fh.node.root().queueReadAhead(fh.path, lastIdx+1)
+	}
+
+	return fuse.ReadResultData(out), 0
+}
+
+// writeChunked applies data to the chunk LRU, extending the tracked file
+// size and marking every touched chunk dirty for the next Flush.
+func (fh *FileHandle) writeChunked(ctx context.Context, data []byte, off int64) (uint32, syscall.Errno) {
+	if _, err := fh.fileSize(ctx); err != nil {
+		return 0, mapError(err)
+	}
+
+	end := off + int64(len(data))
+	written := 0
+	for pos := off; pos < end; {
+		idx := pos / chunkSize
+		entry, err := fh.chunkAt(ctx, idx)
+		if err != nil {
+			return 0, mapError(err)
+		}
+		chunkOff := pos - idx*chunkSize
+		n := int64(chunkSize) - chunkOff
+		if pos+n > end {
+			n = end - pos
+		}
+		copy(entry.data[chunkOff:chunkOff+n], data[written:])
+		entry.dirty = true
+		written += int(n)
+		pos += n
+	}
+
+	if end > fh.size {
+		fh.size = end
+	}
+	return uint32(written), 0
+}
+
+// flushChunked writes every dirty chunk back in one pipelined round trip,
+// first truncating the backing file to 0 if SetTruncated ran since the last
+// flush (dropping whatever tail the old, possibly larger, file still has).
+func (fh *FileHandle) flushChunked(ctx context.Context) syscall.Errno {
+	var dirty []*chunkEntry
+	for e := fh.lru.Front(); e != nil; e = e.Next() {
+		entry := e.Value.(*chunkEntry)
+		if entry.dirty {
+			dirty = append(dirty, entry)
+		}
+	}
+	if len(dirty) == 0 && !fh.truncated {
+		return 0
+	}
+
+	if fh.truncated {
+		if err := fh.client.Truncate(ctx, fh.redisPath(), 0); err != nil {
+			return mapError(err)
+		}
+		fh.truncated = false
+	}
+	if len(dirty) == 0 {
+		fh.node.root().invalidatePath(fh.path)
+		return 0
+	}
+
+	// An encrypted mount's NewBatch still pipelines against the
+	// plaintext-Redis Client it wraps, not against ciphertext, so it can't
+	// be used here: fall back to flushing each chunk with its own
+	// WriteRange call (which does go through the cryptor). This trades
+	// pipelining for correctness; a crypto-aware Batch is future work.
+	if fh.node.root().cryptor != nil {
+		for _, entry := range dirty {
+			if err := fh.flushChunk(ctx, entry); err != nil {
+				return mapError(err)
+			}
+		}
+		fh.truncated = false
+		fh.node.root().invalidatePath(fh.path)
+		return 0
+	}
+
+	b := fh.client.NewBatch(ctx)
+	futures := make([]*client.ErrFuture, len(dirty))
+	for i, entry := range dirty {
+		start := entry.idx * chunkSize
+		length := int64(chunkSize)
+		if start+length > fh.size {
+			length = fh.size - start
+		}
+		if length <= 0 {
+			entry.dirty = false
+			continue
+		}
+		futures[i] = b.WriteRange(fh.path, start, entry.data[:length])
+	}
+	if err := b.Exec(); err != nil {
+		return mapError(err)
+	}
+	for i, f := range futures {
+		if f == nil {
+			continue
+		}
+		if err := f.Result(); err != nil {
+			return mapError(err)
+		}
+		dirty[i].dirty = false
+	}
+
+	fh.truncated = false
+	fh.node.root().invalidatePath(fh.path)
+	return 0
 }
@@ -0,0 +1,173 @@
+package redisfs
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// defaultReadAheadWorkers is used when Options.ReadAheadWorkers is unset.
+const defaultReadAheadWorkers = 4
+
+// readAheadQueueSize bounds how many pending prefetch jobs can queue up
+// before new ones are dropped; read-ahead is a latency optimization, not a
+// correctness requirement, so a full queue just means "skip this one".
+const readAheadQueueSize = 256
+
+// readAheadJob is one chunk to prefetch into the shared page cache.
+type readAheadJob struct {
+	path string
+	idx  int64
+}
+
+// bumpGeneration increments path's generation, orphaning any pageCache
+// entries already tagged with the old one. Called by invalidatePath,
+// invalidatePathPrefix, and Setattr's truncate/utimens handling, per the
+// request's "invalidatePath and Utimens bump the generation" requirement.
+func (r *FSRoot) bumpGeneration(path string) {
+	r.genMu.Lock()
+	defer r.genMu.Unlock()
+	if r.generation == nil {
+		r.generation = make(map[string]uint64)
+	}
+	r.generation[path]++
+}
+
+// generationOf returns path's current generation, 0 if it has never been bumped.
+func (r *FSRoot) generationOf(path string) uint64 {
+	r.genMu.Lock()
+	defer r.genMu.Unlock()
+	return r.generation[path]
+}
+
+// pageCacheKey builds the shared page cache key for chunk idx of path at
+// its current generation, so a write that bumps the generation naturally
+// excludes every page cached under the old one.
+func (r *FSRoot) pageCacheKey(path string, idx int64) string {
+	return fmt.Sprintf("%s\x00%d\x00%d", path, r.generationOf(path), idx)
+}
+
+// startBackgroundWorkers launches the read-ahead pool and, if configured,
+// the periodic writeback loop. Like SubscribeInvalidations, these goroutines
+// run for the life of the process; Mount has no corresponding Unmount call
+// that would give them a narrower lifetime to respect.
+func (r *FSRoot) startBackgroundWorkers() {
+	workers := r.opts.ReadAheadWorkers
+	if workers == 0 {
+		workers = defaultReadAheadWorkers
+	}
+	if workers > 0 {
+		r.readAheadCh = make(chan readAheadJob, readAheadQueueSize)
+		for i := 0; i < workers; i++ {
+			go r.readAheadWorker()
+		}
+	}
+
+	if r.opts.WritebackInterval > 0 {
+		go r.writebackLoop()
+	}
+
+	if r.opts.InvalidationPollInterval > 0 {
+		go r.pollInvalidationLoop()
+	}
+}
+
+// pollInvalidationLoop periodically resyncs caches wholesale on
+// Options.InvalidationPollInterval, as a fallback (or backstop) for mounts
+// that can't rely on SubscribeInvalidations to learn about other mounts'
+// writes promptly.
+func (r *FSRoot) pollInvalidationLoop() {
+	ticker := time.NewTicker(r.opts.InvalidationPollInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		r.resyncReason("poll")
+	}
+}
+
+// queueReadAhead schedules a prefetch of chunk idx of path, dropping the job
+// silently if read-ahead is disabled or the queue is full.
+func (r *FSRoot) queueReadAhead(path string, idx int64) {
+	if r.readAheadCh == nil {
+		return
+	}
+	select {
+	case r.readAheadCh <- readAheadJob{path: path, idx: idx}:
+	default:
+	}
+}
+
+// readAheadWorker services read-ahead jobs until the process exits,
+// fetching each chunk via Client.Read and seeding the shared page cache so
+// the FileHandle that eventually asks for it finds a warm entry instead of
+// paying a round trip.
+func (r *FSRoot) readAheadWorker() {
+	for job := range r.readAheadCh {
+		redisPath := r.redisPath(job.path)
+		size, err := r.client.Size(context.Background(), redisPath)
+		if err != nil || size < 0 {
+			continue
+		}
+		start := job.idx * chunkSize
+		if start >= size {
+			continue
+		}
+		length := int64(chunkSize)
+		if start+length > size {
+			length = size - start
+		}
+		key := r.pageCacheKey(job.path, job.idx)
+		if _, ok := r.pageCache.Get(key); ok {
+			continue // another reader already warmed this one
+		}
+		data, err := r.client.Read(context.Background(), redisPath, start, length)
+		if err != nil {
+			continue
+		}
+		r.pageCache.Set(key, data)
+	}
+}
+
+// registerHandle records fh so the writeback loop can find it.
+func (r *FSRoot) registerHandle(fh *FileHandle) {
+	r.handlesMu.Lock()
+	defer r.handlesMu.Unlock()
+	if r.handles == nil {
+		r.handles = make(map[*FileHandle]struct{})
+	}
+	r.handles[fh] = struct{}{}
+}
+
+// unregisterHandle drops fh from the writeback registry, e.g. on Release.
+func (r *FSRoot) unregisterHandle(fh *FileHandle) {
+	r.handlesMu.Lock()
+	defer r.handlesMu.Unlock()
+	delete(r.handles, fh)
+}
+
+// writebackLoop flushes every open handle's dirty chunks on
+// Options.WritebackInterval, so a long-lived handle's writes reach Redis
+// without waiting for an explicit flush/fsync/close.
+func (r *FSRoot) writebackLoop() {
+	ticker := time.NewTicker(r.opts.WritebackInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		for _, fh := range r.openHandles() {
+			if errno := fh.Flush(context.Background()); errno != 0 {
+				r.opts.Logger.Printf("background writeback of %s failed: %v", fh.path, errno)
+			}
+		}
+	}
+}
+
+// openHandles returns a snapshot of the currently registered handles, so
+// writebackLoop doesn't hold handlesMu while flushing (which can block on
+// Redis).
+func (r *FSRoot) openHandles() []*FileHandle {
+	r.handlesMu.Lock()
+	defer r.handlesMu.Unlock()
+	handles := make([]*FileHandle, 0, len(r.handles))
+	for fh := range r.handles {
+		handles = append(handles, fh)
+	}
+	return handles
+}
@@ -10,19 +10,26 @@ import (
 
 // Symlink implements fs.NodeSymlinker.
 func (n *FSNode) Symlink(ctx context.Context, target, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	defer n.root().metrics.Observe("symlink")()
+
 	if n.opts.ReadOnly {
 		return nil, syscall.EROFS
 	}
 
 	child := n.newChild(name)
 
-	if err := n.client.Ln(ctx, target, child.fsPath); err != nil {
+	encTarget := target
+	if cryptor := n.root().cryptor; cryptor != nil {
+		encTarget = cryptor.EncryptName(target)
+	}
+
+	if err := n.client.Ln(ctx, encTarget, child.redisPath()); err != nil {
 		return nil, mapError(err)
 	}
 
 	n.root().invalidatePath(child.fsPath)
 
-	st, err := n.client.Stat(ctx, child.fsPath)
+	st, err := n.client.Stat(ctx, child.redisPath())
 	if err != nil {
 		return nil, mapError(err)
 	}
@@ -32,15 +39,24 @@ func (n *FSNode) Symlink(ctx context.Context, target, name string, out *fuse.Ent
 	out.SetAttrTimeout(n.opts.AttrTimeout)
 
 	node := n.NewInode(ctx, child, fs.StableAttr{Mode: syscall.S_IFLNK})
+	n.root().registerInode(child.fsPath, node)
 	return node, 0
 }
 
 // Readlink implements fs.NodeReadlinker.
 func (n *FSNode) Readlink(ctx context.Context) ([]byte, syscall.Errno) {
-	target, err := n.client.Readlink(ctx, n.fsPath)
+	defer n.root().metrics.Observe("readlink")()
+
+	target, err := n.client.Readlink(ctx, n.redisPath())
 	if err != nil {
 		return nil, mapError(err)
 	}
+	if cryptor := n.root().cryptor; cryptor != nil {
+		target, err = cryptor.DecryptName(target)
+		if err != nil {
+			return nil, syscall.EIO
+		}
+	}
 	return []byte(target), 0
 }
 